@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// subcommands are run as `go run . <name> ...` from the cli folder. Anything
+// else falls through to the calculator flags in main.
+var subcommands = map[string]func(args []string) error{
+	"pricedata":   runPriceData,
+	"update":      runUpdate,
+	"sensitivity": runSensitivity,
+	"tax":         runTax,
+}
+
+// runSubcommand runs the subcommand named by args[0], if there is one, and
+// reports whether it did. A failing subcommand exits non-zero.
+func runSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		return false
+	}
+	if err := cmd(args[1:]); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	return true
+}
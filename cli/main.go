@@ -1,18 +1,23 @@
 package main
 
 import (
+	"Mining-Profitability/pkg/calc"
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/utils"
 	"flag"
 	"fmt"
 	"image/color"
 	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
 	"os"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/font"
@@ -24,112 +29,299 @@ import (
 )
 
 func main() {
-	var slushToken, messariApiKey, startDate, endedDate string
-	var kwhPrice, watts, uptimePercent, fixedCosts, bitcoinMined, electricCosts, salePrice float64
+	if runSubcommand(os.Args[1:]) {
+		return
+	}
+
+	var configFile, slushToken, messariApiKey, startDate, endedDate, asOfDate, priceSource, priceField, currency, minedLedger, machinesFile, simulate, depreciation, priceIndex, tariffFile, heatMonths, heatDegreeDays, fuelUnit, payoutScheme, salesFile, strategyNames string
+	var paths, blockDays, payouts int
+	var seed int64
+	var kwhPrice, watts, uptimePercent, fixedCosts, bitcoinMined, electricCosts, salePrice, hashrate float64
+	var difficultyGrowth, priceGrowth, projectedPrice float64
+	var hardwareValue, lifeYears, salvagePercent, depreciationRate float64
+	var fullHeatDegreeDays, heaterEfficiency, fuelPrice, heatUsedPercent float64
+	var poolFee, withdrawalFee, txFeePercent float64
 	var hideBitcoinOnGraph bool
+	flag.StringVar(&configFile, "config", "../config.yaml", "Specify path to config file for price data locations.")
 	flag.StringVar(&slushToken, "slushToken", "default-token", "Specify Slush Pool token.")
 	flag.Float64Var(&kwhPrice, "kwhPrice", 0.15, "Specify price paid per kilowatt hour.")
 	flag.Float64Var(&watts, "watts", 3200, "Specify watts used in total.")
+	flag.Float64Var(&hashrate, "hashrate", 0, "Specify total hashrate of your miners in TH/s to compare what was mined with what network difficulty says it should have been.")
 	flag.Float64Var(&uptimePercent, "uptimePercent", 100.0, "Specify percent uptime of your miners.")
 	flag.Float64Var(&fixedCosts, "fixedCosts", 6295.55, "Specify mining setup fix costs.")
 	flag.Float64Var(&bitcoinMined, "bitcoinMined", 0, "Specify total bitcoin mined (use whole bitcoin units not bitcoin).")
+	flag.StringVar(&minedLedger, "minedLedger", "", "Specify path to a CSV of mined bitcoin by day with a date,bitcoin header. Overrides bitcoinMined and plots the mined curve.")
+	flag.StringVar(&payoutScheme, "payoutScheme", "", "Specify the pool payout scheme the coins were mined with: "+strings.Join(calc.PayoutSchemes(), ", ")+". Reports gross and net mined bitcoin.")
+	flag.Float64Var(&poolFee, "poolFee", 0, "Specify the pool's fee in percent. bitcoinMined is what the pool credited after it.")
+	flag.Float64Var(&withdrawalFee, "withdrawalFee", 0, "Specify the on-chain fee in bitcoin each payout cost, taken off bitcoinMined.")
+	flag.IntVar(&payouts, "payouts", 0, "Specify how many payouts there were. Defaults to the number of minedLedger entries.")
+	flag.Float64Var(&txFeePercent, "txFeePercent", 0, "Specify the transaction fees blocks carry as a percent of the subsidy, paid by every scheme but pps.")
+	flag.StringVar(&salesFile, "sales", "", "Specify path to a CSV of mined bitcoin sold with a date,bitcoin,proceeds header. Compares what is still held with strategies that sold for the same cash.")
+	flag.StringVar(&machinesFile, "machines", "", "Specify path to a CSV of machines with model,purchaseDate,purchasePrice,watts,hashrateTHs,decommissionDate,resalePrice columns. Replaces watts, fixedCosts and hashrate.")
+	flag.StringVar(&tariffFile, "tariff", "", "Specify path to a YAML or JSON electricity tariff to price each day's electricity with instead of kwhPrice. Prints the monthly bills.")
+	flag.StringVar(&heatMonths, "heatMonths", "", "Specify the heating season as comma separated months (1-12) to credit the heat the miners replace against electricity.")
+	flag.StringVar(&heatDegreeDays, "heatDegreeDays", "", "Specify the heating season as 12 comma separated monthly heating degree day totals from January instead of heatMonths.")
+	flag.Float64Var(&fullHeatDegreeDays, "fullHeatDegreeDays", 20, "Specify the heating degree days in a day cold enough to use all the miners' heat.")
+	flag.Float64Var(&heaterEfficiency, "heaterEfficiency", 100, "Specify the percent efficiency of the heating the miners replace, e.g. 95 for a gas furnace or 300 for a heat pump.")
+	flag.Float64Var(&fuelPrice, "fuelPrice", 0, "Specify the price of the fuel the replaced heating burns, per fuelUnit.")
+	flag.StringVar(&fuelUnit, "fuelUnit", "kwh", "Specify the unit fuelPrice is per: "+strings.Join(calc.FuelUnits(), ", ")+".")
+	flag.Float64Var(&heatUsedPercent, "heatUsedPercent", 100, "Specify the percent of the miners' heat put to use in the heating season.")
 	flag.Float64Var(&electricCosts, "electricCosts", 0, "Specify total amount spent on electricity")
 	flag.StringVar(&startDate, "startDate", "01/01/2022", "Specify start date of mining operation.")
 	flag.StringVar(&endedDate, "endedDate", "01/01/2022", "Specify ended date of mining operation.")
+	flag.StringVar(&asOfDate, "asOfDate", "", "Compute everything as of this past date (mm/dd/yyyy), valuing coins at that day's price. Pass bitcoinMined as of that date.")
+	flag.StringVar(&currency, "currency", "", "Currency costs are entered in and results are shown in, e.g. EUR. Defaults to the currency in the config.")
 	flag.Float64Var(&salePrice, "salePrice", 0, "Price from sales of hardware")
+	flag.Float64Var(&hardwareValue, "hardwareValue", 0, "Specify what the hardware still held could be sold for today. Overrides depreciation.")
+	flag.StringVar(&depreciation, "depreciation", "", "Specify how hardware loses value to work out what it could be sold for: straight-line, declining-balance or index.")
+	flag.Float64Var(&lifeYears, "lifeYears", 3, "Specify the years straight-line depreciation takes to reach the salvage value.")
+	flag.Float64Var(&salvagePercent, "salvagePercent", 0, "Specify the percent of its cost hardware is never worth less than.")
+	flag.Float64Var(&depreciationRate, "depreciationRate", 0, "Specify the yearly percent declining-balance depreciation loses. Defaults to 200 / lifeYears.")
+	flag.StringVar(&priceIndex, "priceIndex", "", "Specify path to a CSV of a used ASIC price index with a date,value header for index depreciation.")
+	flag.Float64Var(&difficultyGrowth, "difficultyGrowth", 0, "Specify yearly percent network difficulty grows by in the breakeven projection. Defaults to projectionDifficultyGrowthPercent in the config.")
+	flag.Float64Var(&priceGrowth, "priceGrowth", 0, "Specify yearly percent bitcoin price grows by in the breakeven projection. Defaults to projectionPriceGrowthPercent in the config.")
+	flag.Float64Var(&projectedPrice, "projectedPrice", 0, "Specify price the breakeven projection starts from instead of the current price.")
+	flag.StringVar(&simulate, "simulate", "", "Specify a Monte Carlo price model to forecast breakeven with: gbm or bootstrap. Adds a fan chart to the graph.")
+	flag.IntVar(&paths, "paths", 1000, "Specify how many price paths the simulation runs.")
+	flag.Int64Var(&seed, "seed", 0, "Specify the simulation's random seed to repeat a run. 0 picks one.")
+	flag.IntVar(&blockDays, "blockDays", 30, "Specify how many consecutive days of returns the bootstrap simulation replays at a time.")
 	flag.StringVar(&messariApiKey, "messariApiKey", "default", "Specify Messari API Key")
+	flag.StringVar(&priceSource, "priceSource", pricedata.SourceKraken, "Specify historical price source: kraken, coinbase, median or blended.")
+	flag.StringVar(&priceField, "priceField", pricedata.FieldOpen, "Specify which daily price strategies buy at: open, close, typical or vwap.")
+	flag.StringVar(&strategyNames, "strategies", "", "Specify comma separated strategies to compare mining with: "+strings.Join(calc.StrategyNames(), ", ")+". Defaults to all of them.")
 	flag.BoolVar(&hideBitcoinOnGraph, "hideBitcoinOnGraph", false, "Will hide bitcoin on y-axis of graph, good for opsec when sharing the image. true to hide, false to keep the figure displayed")
 
 	flag.Parse()
-	if slushToken == "default-token" && bitcoinMined == 0 {
-		fmt.Printf("Must enter either slush api token or bitcoinMined")
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if slushToken == "default-token" && bitcoinMined == 0 && minedLedger == "" {
+		fmt.Printf("Must enter either slush api token, bitcoinMined or minedLedger")
+	}
+	request := calc.RequestPayload{
+		StartDate:          startDate,
+		KwhPrice:           kwhPrice,
+		Watts:              watts,
+		UptimePercent:      uptimePercent,
+		FixedCosts:         fixedCosts,
+		BitcoinMined:       bitcoinMined,
+		MessariApiKey:      messariApiKey,
+		HideBitcoinOnGraph: hideBitcoinOnGraph,
+		PriceSource:        priceSource,
+		PriceField:         priceField,
+		AsOfDate:           asOfDate,
+		Currency:           currency,
+		HashrateTHs:        hashrate,
+		ProjectedPrice:     projectedPrice,
+		SalePrice:          salePrice,
+		HardwareValue:      hardwareValue,
+	}
+	if slushToken != "default-token" {
+		request.SlushToken = &slushToken
+	}
+	if setFlags["endedDate"] {
+		request.EndDate = endedDate
+	}
+	if electricCosts != 0 {
+		request.ElectricCosts = &electricCosts
+	}
+	if setFlags["difficultyGrowth"] {
+		request.DifficultyGrowthPercent = &difficultyGrowth
+	}
+	if setFlags["priceGrowth"] {
+		request.PriceGrowthPercent = &priceGrowth
+	}
+	if strategyNames != "" {
+		request.Strategies = strings.Split(strategyNames, ",")
+	}
+	if simulate != "" {
+		request.Simulation = &calc.SimulationRequest{Model: simulate, Paths: paths, Seed: seed, BlockDays: blockDays}
+	}
+	if payoutScheme != "" {
+		request.Pool = &calc.PoolPayout{PoolOption: calc.PoolOption{Scheme: payoutScheme, FeePercent: poolFee, WithdrawalFee: withdrawalFee}, Payouts: payouts, TxFeePercent: txFeePercent}
+	}
+	var err error
+	if minedLedger != "" {
+		if request.MinedLedgerCsv, err = ReadFile(minedLedger); err != nil {
+			fmt.Printf("Error reading mined ledger: %s\n", err.Error())
+			return
+		}
+	}
+	if salesFile != "" {
+		if request.SalesCsv, err = ReadFile(salesFile); err != nil {
+			fmt.Printf("Error reading sales: %s\n", err.Error())
+			return
+		}
+	}
+	if machinesFile != "" {
+		if request.Machines, err = LoadMachines(machinesFile); err != nil {
+			fmt.Printf("Error reading machines: %s\n", err.Error())
+			return
+		}
+		if !setFlags["startDate"] {
+			// the fleet's first purchase
+			request.StartDate = ""
+		}
+	}
+	if tariffFile != "" {
+		if request.Tariff, err = calc.LoadTariff(tariffFile); err != nil {
+			fmt.Printf("Error with tariff: %s\n", err.Error())
+			return
+		}
 	}
-	price, err := GetBitcoinPrice()
+	if heatMonths != "" || heatDegreeDays != "" {
+		heat := calc.HeatReuse{FullHeatDegreeDays: fullHeatDegreeDays, EfficiencyPercent: heaterEfficiency, FuelPrice: fuelPrice, FuelUnit: fuelUnit, UsedPercent: heatUsedPercent}
+		if heat.Months, heat.DegreeDays, err = HeatSeason(heatMonths, heatDegreeDays); err != nil {
+			fmt.Printf("Error with heat reuse: %s\n", err.Error())
+			return
+		}
+		request.HeatReuse = &heat
+	}
+	if depreciation != "" {
+		schedule := calc.DepreciationSchedule{Method: depreciation, LifeYears: lifeYears, SalvagePercent: salvagePercent, RatePercent: depreciationRate}
+		if priceIndex != "" {
+			if schedule.IndexCsv, err = ReadFile(priceIndex); err != nil {
+				fmt.Printf("Error reading the price index: %s\n", err.Error())
+				return
+			}
+		}
+		request.Depreciation = &schedule
+	}
+
+	cfg, err := config.New(configFile)
 	if err != nil {
-		fmt.Printf("Error getting bitcoin price: %s\n", err.Error())
+		fmt.Printf("Error loading config: %s\n", err.Error())
 		return
 	}
-	fmt.Printf("Bicoin current price: $%s\n", fmt.Sprintf("%.2f", price))
-
-	operationalDays, err := OperationDays(startDate, endedDate)
+	priceSources, err := pricedata.LoadSources(cfg)
 	if err != nil {
-		fmt.Printf("Error calculating operation days start: %s\n", err.Error())
+		fmt.Printf("Error loading price data: %s\n", err.Error())
+		return
+	}
+	externalData, err := externaldata.New(cfg, priceSources)
+	if err != nil {
+		fmt.Printf("Error setting up external data: %s\n", err.Error())
+		return
+	}
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	calcClient := calc.New(cfg, logger)
+	stats, err := calcClient.GenerateStats(request, externalData, utils.New())
+	if err != nil {
+		fmt.Printf("Error generating the report: %s\n", err.Error())
 		return
 	}
 
-	fmt.Printf("Operational Days: %s\n", fmt.Sprintf("%.2f", operationalDays))
-
-	if slushToken != "default-token" {
-		bitcoinMined, err = GetUserMinedCoinsTotal(slushToken)
-		if err != nil {
-			fmt.Printf("Error GetUserMinedCoinsTotal: %s\n", err.Error())
-		}
+	currency = stats.Currency
+	if asOfDate != "" {
+		fmt.Printf("Bicoin price on %s: %s %s\n", stats.AsOfDate, fmt.Sprintf("%.2f", stats.BitcoinPrice), currency)
+	} else {
+		fmt.Printf("Bicoin current price: %s %s\n", fmt.Sprintf("%.2f", stats.BitcoinPrice), currency)
 	}
-	fmt.Printf("Average coins per day: %s\n", fmt.Sprintf("%.8f", AverageCoinsPerDay(operationalDays, bitcoinMined)))
-	dollarinosEarned := DollarinosEarned(bitcoinMined, price)
-	fmt.Printf("Dollar value of bitcoin mined: $%s\n", fmt.Sprintf("%.2f", dollarinosEarned))
-	if electricCosts == 0 {
-		electricCosts = ElectricCosts(kwhPrice, uptimePercent, operationalDays, watts)
-	}
-	fmt.Printf("Total electric costs: $%s\n", fmt.Sprintf("%.2f", electricCosts))
-	percentPaidOff := PercentPaidOff(dollarinosEarned, fixedCosts, electricCosts, salePrice)
-	fmt.Printf("Percent paid off: %s%%\n", fmt.Sprintf("%.2f", percentPaidOff))
-	fmt.Printf("Bitcoin percentage increase needed to be breakeven: %s%%\n", fmt.Sprintf("%.2f", ((100/percentPaidOff)-1)*100))
-	breakevenPrice := BreakEvenPrice(percentPaidOff, price)
-	fmt.Printf("Breakeven price: $%s\n", fmt.Sprintf("%.2f", breakevenPrice))
-	daysUntilBreakeven := DaysUntilBreakeven(operationalDays, percentPaidOff)
-	if endedDate == "" {
-		fmt.Printf("Expected more days until breakeven: %s\n", fmt.Sprintf("%.2f", daysUntilBreakeven))
-		fmt.Printf("Total mining days (past + future) to breakeven: %s\n", fmt.Sprintf("%.2f", daysUntilBreakeven+operationalDays))
-		futureDate, err := DateFromDaysNow(daysUntilBreakeven)
-		if err != nil {
-			fmt.Printf("Error with DateFromDaysNow. Error: %s\n", err.Error())
-			return
+	fmt.Printf("Operational Days: %s\n", fmt.Sprintf("%.2f", stats.DaysSinceStarted))
+	if fleet := stats.Fleet; fleet != nil {
+		fmt.Printf("Machines running: %d, %s W, %s TH/s\n", fleet.Running, fmt.Sprintf("%.0f", fleet.Watts), fmt.Sprintf("%.2f", fleet.HashrateTHs))
+		fmt.Printf("Machines bought: %s %s, sold: %s %s\n", fmt.Sprintf("%.2f", fleet.Purchases), currency, fmt.Sprintf("%.2f", fleet.Resales), currency)
+	}
+	for _, bill := range stats.ElectricBills {
+		fmt.Printf("Electric bill %s: %s %s (%s kWh, energy %s, fixed %s, demand %s)\n", bill.Month, fmt.Sprintf("%.2f", bill.Total), currency, fmt.Sprintf("%.0f", bill.Kwh), fmt.Sprintf("%.2f", bill.Energy), fmt.Sprintf("%.2f", bill.Fixed), fmt.Sprintf("%.2f", bill.Demand))
+	}
+	if pool := stats.Pool; pool != nil {
+		fmt.Printf("Gross bitcoin mined: %s (pool fees %s, %d payouts costing %s)\n", fmt.Sprintf("%.8f", pool.GrossBitcoin), fmt.Sprintf("%.8f", pool.PoolFees), pool.Payouts, fmt.Sprintf("%.8f", pool.WithdrawalFees))
+		fmt.Printf("Net bitcoin mined: %s\n", fmt.Sprintf("%.8f", pool.NetBitcoin))
+	}
+	if stats.NetworkDataStale {
+		last, _ := priceSources.Network.Last()
+		fmt.Printf("Network difficulty data ends %s, leaving out expected mining and the projection\n", last.Time().Format("01/02/2006"))
+	}
+	if stats.ExpectedData != nil {
+		fmt.Printf("Expected bitcoin mined: %s\n", fmt.Sprintf("%.8f", stats.ExpectedBitcoinMined))
+		if stats.ExpectedBitcoinMined > 0 {
+			fmt.Printf("Mined vs expected: %s%%\n", fmt.Sprintf("%.2f", stats.MinedVsExpected))
 		}
-		if endedDate != "" {
-			fmt.Printf("Expected breakeven date: %s\n", futureDate)
+		if pool := stats.Pool; pool != nil {
+			fmt.Printf("Expected from %s: %s (luck %s%%)\n", pool.Scheme, fmt.Sprintf("%.8f", pool.ExpectedBitcoin), fmt.Sprintf("%.2f", pool.LuckPercent))
+			for _, estimate := range pool.Estimates {
+				fmt.Printf("Expected from %s at %s%% fee: %s (%s)\n", estimate.Name, fmt.Sprintf("%.2f", estimate.FeePercent), fmt.Sprintf("%.8f", estimate.ExpectedBitcoin), fmt.Sprintf("%+.8f", estimate.Change))
+				if estimate.Scheme == calc.SchemeSolo {
+					fmt.Printf("Chance solo mining found a block: %s%%\n", fmt.Sprintf("%.2f", estimate.BlockChancePercent))
+				}
+			}
 		}
 	}
+	fmt.Printf("Average coins per day: %s\n", fmt.Sprintf("%.8f", stats.AverageCoinsPerDay))
+	fmt.Printf("Value of bitcoin mined: %s %s\n", fmt.Sprintf("%.2f", stats.DollarinosEarned), currency)
+	if heat := stats.HeatReuse; heat != nil {
+		fmt.Printf("Heat reuse credit: %s %s over %d heating days (%s kWh of heat, %s%% of the electricity)\n", fmt.Sprintf("%.2f", heat.Credit), currency, heat.HeatingDays, fmt.Sprintf("%.0f", heat.HeatKwh), fmt.Sprintf("%.2f", heat.SavedPercent))
+		fmt.Printf("Net cost without heat credit: %s %s, with: %s %s\n", fmt.Sprintf("%.2f", heat.CostWithoutCredit), currency, fmt.Sprintf("%.2f", heat.CostWithCredit), currency)
+	}
+	fmt.Printf("Total electric costs: %s %s\n", fmt.Sprintf("%.2f", stats.ElectricCosts), currency)
+	fmt.Printf("Percent paid off: %s%%\n", fmt.Sprintf("%.2f", stats.PercentPaidOff))
+	if stats.LiquidationValue > 0 {
+		fmt.Printf("Hardware liquidation value: %s %s\n", fmt.Sprintf("%.2f", stats.LiquidationValue), currency)
+		fmt.Printf("Percent paid off with hardware: %s%%\n", fmt.Sprintf("%.2f", stats.PercentPaidOffWithHardware))
+	}
+	if realized := stats.Realized; realized != nil {
+		fmt.Printf("Realized profit: %s %s (%s%%)\n", fmt.Sprintf("%.2f", realized.Profit), currency, fmt.Sprintf("%.2f", realized.ReturnPercent))
+	}
+	fmt.Printf("Bitcoin percentage increase needed to be breakeven: %s%%\n", fmt.Sprintf("%.2f", stats.BreakevenPriceIncrease))
+	fmt.Printf("Breakeven price: %s %s\n", fmt.Sprintf("%.2f", stats.BreakevenPrice), currency)
+	if stats.Realized == nil {
+		fmt.Printf("Expected more days until breakeven: %s\n", fmt.Sprintf("%.2f", stats.DaysUntilBreakeven))
+		fmt.Printf("Total mining days (past + future) to breakeven: %s\n", fmt.Sprintf("%.2f", stats.TotalMiningDaysToBreakEven))
+		fmt.Printf("Expected breakeven date: %s\n", stats.ExpectedBreakevenDate)
+	}
 	fmt.Printf("\n\n------------------------------------------------\n\n")
-	dailyElectricCost := electricCosts / operationalDays
-	fmt.Printf("Electric costs per day: $%s\n", fmt.Sprintf("%.2f", dailyElectricCost))
-	unixTimeStampStart, err := DateToUnixTimestamp(startDate)
-	if err != nil {
-		fmt.Printf("Error with DateToUnixTimestamp: %s\n", err.Error())
+	fmt.Printf("Electric costs per day: %s %s\n", fmt.Sprintf("%.2f", stats.DailyElectricCost), currency)
+	if projection := stats.Projection; projection != nil {
+		difficultyGrowth, priceGrowth = calcClient.DifficultyGrowthPercent, calcClient.PriceGrowthPercent
+		if request.DifficultyGrowthPercent != nil {
+			difficultyGrowth = *request.DifficultyGrowthPercent
+		}
+		if request.PriceGrowthPercent != nil {
+			priceGrowth = *request.PriceGrowthPercent
+		}
+		fmt.Printf("Next halving: block %d, around %s\n", projection.NextHalvingHeight, projection.NextHalvingDate)
+		if projection.BreakevenDate == "" {
+			fmt.Printf("Projected breakeven: not within %d days (difficulty +%s%%/yr, price +%s%%/yr)\n", calcClient.ProjectionDays, fmt.Sprintf("%.1f", difficultyGrowth), fmt.Sprintf("%.1f", priceGrowth))
+		} else {
+			fmt.Printf("Projected breakeven date: %s, %s more days (difficulty +%s%%/yr, price +%s%%/yr)\n", projection.BreakevenDate, fmt.Sprintf("%.0f", projection.DaysUntilBreakeven), fmt.Sprintf("%.1f", difficultyGrowth), fmt.Sprintf("%.1f", priceGrowth))
+		}
 	}
-	priceData := GetPriceDataFromDateRange(unixTimeStampStart)
-	fiatMoney := electricCosts + fixedCosts - salePrice
-	unixDaysSinceStart, err := RegularDateToUnix(startDate, endedDate)
-	if err != nil {
-		fmt.Printf("error with RegularDateToUnix: %s\n", err)
+	if stats.Simulation != nil {
+		PrintSimulation(stats.Simulation)
 	}
-
-	fmt.Printf("bitcoin mined: %v\n", bitcoinMined)
-	dcaData, dcaBitcoin := DailyDCABuy(fiatMoney, unixDaysSinceStart, priceData)
-	ahData, ahBitcoin := AmericanHodlSlamBuy(fiatMoney, priceData[0], len(priceData))
-	fmt.Printf("AmericanHodl: %v\n", ahBitcoin)
-	fmt.Printf("Daily-DCA: %v\n", dcaBitcoin)
+	fmt.Printf("Price source: %s (%s)\n", stats.PriceSource, stats.PriceField)
+	fmt.Printf("bitcoin mined: %v\n", stats.BitcoinMined)
 	// MessariData(messariApiKey)
-	antiHomeMinerData, antiHomeMinerBitcoin := AntiHomeMiner(fixedCosts, electricCosts, unixDaysSinceStart, priceData)
-	fmt.Printf("Anti-Miner: %v\n", antiHomeMinerBitcoin)
-	MakePlot(ahData, dcaData, antiHomeMinerData, bitcoinMined, hideBitcoinOnGraph)
+	for _, strategy := range stats.Strategies {
+		bought := strategy.Bitcoin
+		if stats.Sales != nil {
+			// before selling for the same cash as the sales
+			bought += stats.Sales.StrategySold[strategy.Name]
+		}
+		fmt.Printf("%s: %v\n", strategy.Name, bought)
+	}
+	minedData := stats.MinedData
+	if sales := stats.Sales; sales != nil {
+		minedData = sales.HeldData
+		fmt.Printf("Bitcoin sold: %s for %s %s\n", fmt.Sprintf("%.8f", sales.BitcoinSold), fmt.Sprintf("%.2f", sales.Proceeds), currency)
+		fmt.Printf("Bitcoin held: %s, cash position: %s %s\n", fmt.Sprintf("%.8f", sales.HeldBitcoin), fmt.Sprintf("%.2f", sales.Cash), currency)
+		fmt.Printf("After selling for the same cash:\n")
+		for _, strategy := range stats.Strategies {
+			fmt.Printf("%s: %v\n", strategy.Name, strategy.Bitcoin)
+		}
+	}
+	var fan *calc.FanChart
+	if stats.Simulation != nil {
+		fan = &stats.Simulation.Fan
+	}
+	MakePlot(stats.Strategies, minedData, stats.ExpectedData, fan, hideBitcoinOnGraph)
 	fmt.Printf("\n\n------------------------------------------------\n\n")
 	fmt.Printf("Percentage comparison of strategies versus mining. \n\n")
-	rankings := map[float64]string{ahBitcoin: "AmericanHodl",
-		dcaBitcoin:           "Daily-DCA",
-		antiHomeMinerBitcoin: "Anti-Miner",
+	for _, ranking := range stats.Rankings {
+		fmt.Printf("%s: %.2f%%\n", ranking.Name, ranking.Percent)
 	}
-	CompareStrategies(bitcoinMined, rankings)
-
-}
 
-func DateToUnixTimestamp(start string) (timestamp string, err error) {
-	t, err := time.Parse("01/02/2006", start)
-	if err != nil {
-		return
-	}
-	b := t.Unix()
-	return strconv.FormatInt(b, 10), err
 }
 
 func MessariData(apiKey string) {
@@ -171,226 +363,17 @@ func MessariData(apiKey string) {
 	}
 }
 
-func GetBitcoinPrice() (price float64, err error) {
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
-	req, err := http.NewRequest("GET", "https://blockchain.info/tobtc?currency=USD&value=500", nil)
-	if err != nil {
-		fmt.Printf("Got error %s", err.Error())
-		return
-	}
-	response, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Got error %s", err.Error())
-		return
-	}
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer response.Body.Close()
-	s, err := strconv.ParseFloat(string(body), 64)
-	if err != nil {
-		return
-	}
-	price = 500 / s
-	return
-}
-
-func AverageCoinsPerDay(days, coins float64) (averageCoinsPerDay float64) {
-	return coins / days
-}
-
-func DollarinosEarned(coins, price float64) (dollarinos float64) {
-	return coins * price
-}
-
-func ElectricCosts(kwhPrice, uptimePercentage, uptimeDays, watts float64) (electricCosts float64) {
-	kwhPerDay := watts * 24 / 1000
-	electricCosts = kwhPrice * kwhPerDay * (uptimePercentage / 100) * uptimeDays
-	return
-}
-
-func PercentPaidOff(dollarinosEarned, fixedCosts, variableCosts, salePrice float64) (percentPaidOff float64) {
-	return dollarinosEarned / (fixedCosts + variableCosts - salePrice) * 100
-}
-
-func OperationDays(start, end string) (float64, error) {
-	if end != "" {
-		return DaysBetweenDates(start, end)
-	}
-	return DaysSinceStart(start)
-}
-
-func DaysSinceStart(startDate string) (days float64, err error) {
-	t, err := time.Parse("01/02/2006", startDate)
-	if err != nil {
-		return
-	}
-	durationSinceStart := time.Since(t)
-	days = durationSinceStart.Hours() / 24
-	return
-}
-
-func DaysBetweenDates(start, end string) (days float64, err error) {
-	startTime, err := time.Parse("01/02/2006", start)
-	if err != nil {
-		return
-	}
-
-	endTime, err := time.Parse("01/02/2006", end)
-	if err != nil {
-		return
-	}
-	durationSinceStart := endTime.Sub(startTime)
-	days = durationSinceStart.Hours() / 24
-	return
-}
-
-func RegularDateToUnix(start, end string) (float64, error) {
-	if end != "" {
-		startTime, err := time.Parse("01/02/2006", start)
-		if err != nil {
-			return 0, err
-		}
-
-		endTime, err := time.Parse("01/02/2006", end)
-		if err != nil {
-			return 0, err
-		}
-		startUnixSeconds := startTime.Unix()
-		endUnixSeconds := endTime.Unix()
-		startUnixTine := time.Unix(startUnixSeconds, 0)
-		endUnixTime := time.Unix(endUnixSeconds, 0)
-		durationSinceStart := endUnixTime.Sub(startUnixTine)
-		days := durationSinceStart.Hours() / 24
-		return math.Floor(days), err
-	} else {
-		t, err := time.Parse("01/02/2006", start)
-		if err != nil {
-			return 0, err
+// PrintSimulation prints a Monte Carlo breakeven forecast.
+func PrintSimulation(s *calc.Simulation) {
+	fmt.Printf("Simulated %d %s price paths (seed %d), drift %s%%/yr, volatility %s%%/yr\n", s.Paths, s.Model, s.Seed, fmt.Sprintf("%.1f", s.Drift*100), fmt.Sprintf("%.1f", s.Volatility*100))
+	fmt.Printf("Chance of breakeven within 6 months: %s%%, 1 year: %s%%, 2 years: %s%%\n", fmt.Sprintf("%.1f", s.BreakevenProbability6Months), fmt.Sprintf("%.1f", s.BreakevenProbability1Year), fmt.Sprintf("%.1f", s.BreakevenProbability2Years))
+	for _, p := range []string{"p10", "p25", "p50", "p75", "p90"} {
+		date := s.BreakevenPercentiles[p]
+		if date == "" {
+			date = "not within the projection"
 		}
-		b := t.Unix()
-		tm := time.Unix(b, 0)
-		durationSinceStart := time.Since(tm)
-		days := durationSinceStart.Hours() / 24
-		return math.Floor(days), err
-	}
-}
-
-func BreakEvenPrice(percentPaidOff, bitcoinPrice float64) (breakevenPrice float64) {
-	return bitcoinPrice * (1 / (percentPaidOff / 100))
-}
-
-func GetUserMinedCoinsTotal(token string) (coins float64, err error) {
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
-	req, err := http.NewRequest("GET", "https://slushpool.com/accounts/profile/json/btc/", nil)
-	if err != nil {
-		fmt.Printf("Got error making request to https://slushpool.com/accounts/profile/json/btc/ Error: %s\n", err.Error())
-		return
+		fmt.Printf("Breakeven %s: %s\n", p, date)
 	}
-	req.Header.Set("SlushPool-Auth-Token", token)
-	response, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Got error doing request to slush endpoint https://slushpool.com/accounts/profile/json/btc/ Error: %s\n", err.Error())
-		return
-	}
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Printf("Error rading body from http call to https://slushpool.com/accounts/profile/json/btc/  Error: %s\n", err.Error())
-		return
-	}
-	defer response.Body.Close()
-	value := gjson.GetBytes(body, "btc")
-	allTimeReward, err := strconv.ParseFloat(value.Get("all_time_reward").String(), 64)
-	if err != nil {
-		fmt.Printf("Error converting all_time_reward to float: %s\n", err.Error())
-		return
-	}
-
-	unconfirmedCoins, err := strconv.ParseFloat(value.Get("unconfirmed_reward").String(), 64)
-	if err != nil {
-		fmt.Printf("Error converting unconfirmed_reward to float: %s\n", err.Error())
-		return
-	}
-
-	coins = allTimeReward + unconfirmedCoins
-	return coins, err
-}
-
-func DaysUntilBreakeven(daysSinceStart, percentPaidOff float64) (moreDays float64) {
-	moreDays = (daysSinceStart * (1 / (percentPaidOff / 100))) - daysSinceStart
-	return
-}
-
-func DateFromDaysNow(days float64) (futureDate string, err error) {
-	hours := days * 24
-	hourDuration, err := time.ParseDuration(fmt.Sprintf("%f", hours) + "h")
-	if err != nil {
-		return
-	}
-	futureTime := time.Now().Add(hourDuration)
-	futureDate = futureTime.Format("01/02/2006")
-	return
-}
-
-func GetPriceDataFromDateRange(start string) (priceData []float64) {
-	content, err := os.ReadFile("../PriceDataKraken.json")
-	if err != nil {
-		fmt.Printf("Error reading ../PriceDataKraken.json: %s\n", err.Error())
-	}
-	vals := gjson.GetBytes(content, "data").Array()
-	foundStart := false
-	for _, v := range vals {
-		timestamp := v.Get("timestamp").String()
-		price := v.Get("openPrice").Float()
-		if timestamp == start {
-			foundStart = true
-		}
-		if foundStart {
-			// i, err := strconv.ParseInt(v.Get("timestamp").String(), 10, 64)
-			// if err != nil {
-			// 	return
-			// }
-			// tm := time.Unix(i, 0)
-			// fmt.Printf("%v\n", tm)
-			priceData = append(priceData, price)
-		}
-		// fmt.Printf("timestamp: %s price: %v\n", timestamp, price)
-	}
-	return
-}
-
-func AmericanHodlSlamBuy(dollarsAvailable, openPrice float64, numberDays int) (cumulativeTotal []float64, bitcoinAcquired float64) {
-	bitcoinAcquired = dollarsAvailable / openPrice
-	for i := 0; i < numberDays; i++ {
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return
-}
-
-func DailyDCABuy(dollarsAvialble, operationalDays float64, priceData []float64) (cumulativeTotal []float64, bitcoinAcquired float64) {
-	dollarsToSpendPerDay := dollarsAvialble / operationalDays
-	// fmt.Printf("number of days to stack: %v   lenPriceData: %d\n", operationalDays, len(priceData))
-	for _, val := range priceData {
-		bitcoinAcquired += dollarsToSpendPerDay / val
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return
-}
-
-func AntiHomeMiner(fixedCosts, electricCosts, operationalDays float64, priceData []float64) (cumulativeTotal []float64, bitcoinAcquired float64) {
-	bitcoinAcquired += fixedCosts / priceData[0]
-	cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	dollarsToSpendPerDay := electricCosts / operationalDays
-	for _, val := range priceData {
-		bitcoinAcquired += dollarsToSpendPerDay / val
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return
 }
 
 func CompareData() {
@@ -427,16 +410,55 @@ func CompareData() {
 	}
 }
 
-func MakeMinedBitcoinData(ahData []float64, minedBitcoin float64) (minedData []float64) {
-	minedData = []float64{}
-	for range ahData {
-		minedData = append(minedData, minedBitcoin)
+// LoadMachines reads a fleet CSV.
+func LoadMachines(path string) ([]calc.Machine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-	return
+	defer file.Close()
+	return calc.ParseMachinesCSV(file)
 }
 
-func MakePlot(ahData, dcaData, antiMinerData []float64, minedBitcoin float64, hideAxis bool) {
-	minedBitcoinData := MakeMinedBitcoinData(ahData, minedBitcoin)
+// ReadFile returns the contents of the file at path, for the CSV inputs the
+// request takes as text.
+func ReadFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// HeatSeason reads the heating season's comma separated months and monthly
+// heating degree days.
+func HeatSeason(months, degreeDays string) ([]int, []float64, error) {
+	var heatMonths []int
+	var heatDegreeDays []float64
+	for _, field := range strings.Split(months, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		month, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading heatMonths: %w", err)
+		}
+		heatMonths = append(heatMonths, month)
+	}
+	for _, field := range strings.Split(degreeDays, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading heatDegreeDays: %w", err)
+		}
+		heatDegreeDays = append(heatDegreeDays, value)
+	}
+	return heatMonths, heatDegreeDays, nil
+}
+
+func MakePlot(strategies []calc.StrategyResult, minedBitcoinData, expectedData []float64, fan *calc.FanChart, hideAxis bool) {
 	p := plot.New()
 	// p.Y.Tick.Label
 	p.Title.Text = "Bitcoin Acquired Over Time"
@@ -452,17 +474,28 @@ func MakePlot(ahData, dcaData, antiMinerData []float64, minedBitcoin float64, hi
 			Handler: plot.DefaultTextHandler,
 		}
 	}
-	err := plotutil.AddLinePoints(p,
-		"AmericanHodl", plotData(ahData),
-		"Daily DCA", plotData(dcaData),
-		"Anti-Miner", plotData(antiMinerData),
-		"Mined", plotData(minedBitcoinData))
+	lines := []interface{}{}
+	for _, strategy := range strategies {
+		lines = append(lines, strategy.Name, plotData(strategy.Data))
+	}
+	lines = append(lines, "Mined", plotData(minedBitcoinData))
+	if len(expectedData) > 0 {
+		lines = append(lines, "Expected", plotData(expectedData))
+	}
+	err := plotutil.AddLinePoints(p, lines...)
 	if err != nil {
 		panic(err)
 	}
+	if err := calc.AddFanChart(p, len(minedBitcoinData)-1, fan); err != nil {
+		panic(err)
+	}
 
 	// Save the plot to a PNG file.
-	if err := p.Save(4*vg.Inch, 4*vg.Inch, "./points.png"); err != nil {
+	width := 4 * vg.Inch
+	if fan != nil && len(fan.Bands) > 0 {
+		width = 8 * vg.Inch
+	}
+	if err := p.Save(width, 4*vg.Inch, "./points.png"); err != nil {
 		panic(err)
 	}
 }
@@ -475,27 +508,3 @@ func plotData(bitcoinData []float64) plotter.XYs {
 	}
 	return pts
 }
-
-func CompareStrategies(bitcoinMined float64, m map[float64]string) {
-	keys := make([]float64, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	sort.Float64s(keys)
-
-	// reverse the order so its in decreasing order
-	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
-		keys[i], keys[j] = keys[j], keys[i]
-	}
-
-	for _, k := range keys {
-		percentage := k / bitcoinMined
-		switch {
-		case percentage < 1:
-			percentage = -(1 - percentage)
-		case percentage > 1:
-			percentage = percentage - 1
-		}
-		fmt.Printf("%s: %.2f%%\n", m[k], percentage*100)
-	}
-}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/priceprovider"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const priceDataUsage = `usage:
+  pricedata audit [-config ../config.yaml] [-outlierPercent 20] [-repair]
+  pricedata migrate [-config ../config.yaml] -source kraken -to sqlite -toPath ../PriceData.db
+  pricedata providers [-config ../config.yaml] [-from 2022-07-18] [-to 2022-07-27]`
+
+type priceFile struct {
+	name   string
+	path   string
+	store  pricedata.PriceStore
+	points []pricedata.PricePoint
+}
+
+func runPriceData(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(priceDataUsage)
+	}
+	switch args[0] {
+	case "audit":
+		return runPriceDataAudit(args[1:])
+	case "migrate":
+		return runPriceDataMigrate(args[1:])
+	case "providers":
+		return runPriceDataProviders(args[1:])
+	}
+	return fmt.Errorf(priceDataUsage)
+}
+
+func runPriceDataAudit(args []string) error {
+	fs := flag.NewFlagSet("pricedata audit", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	outlierPercent := fs.Float64("outlierPercent", 20, "Flag daily price moves larger than this percentage.")
+	repair := fs.Bool("repair", false, "Write a repaired copy of each source next to it, with gaps filled from the other exchange or by interpolation.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+
+	files := []*priceFile{}
+	for _, source := range pricedata.ConfiguredSources(cfg) {
+		store, err := pricedata.ConfiguredStore(cfg, source)
+		if err != nil {
+			return err
+		}
+		points, err := store.Read()
+		if err != nil {
+			return err
+		}
+		files = append(files, &priceFile{name: source, path: pricedata.ConfiguredPath(cfg, source), store: store, points: points})
+		printAuditReport(store.String(), pricedata.Audit(source, points, *outlierPercent))
+	}
+
+	if !*repair {
+		return nil
+	}
+	for i, f := range files {
+		var reference *pricedata.PriceSeries
+		if len(files) > 1 {
+			other := files[(i+1)%len(files)]
+			reference = pricedata.NewPriceSeries(other.name, other.points)
+		}
+		repaired, changes := pricedata.Repair(f.points, reference)
+		if err := writeRepair(cfg, f, repaired, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPriceDataMigrate(args []string) error {
+	fs := flag.NewFlagSet("pricedata migrate", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	source := fs.String("source", "", "Source to migrate, e.g. kraken or coinbase.")
+	from := fs.String("from", "", "Backend to read from. Defaults to priceDataBackend in the config.")
+	fromPath := fs.String("fromPath", "", "Path to read from. Defaults to the source's path in the config.")
+	to := fs.String("to", "", "Backend to write to: json, csv or sqlite.")
+	toPath := fs.String("toPath", "", "Path to write to.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" || *to == "" || *toPath == "" {
+		return fmt.Errorf("-source, -to and -toPath are required\n%s", priceDataUsage)
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+	if *from != "" {
+		cfg.PriceDataBackend = *from
+	}
+	fromStore, err := pricedata.ConfiguredStore(cfg, *source)
+	if *fromPath != "" {
+		fromStore, err = pricedata.NewStore(cfg.PriceDataBackend, *fromPath, *source, cfg.PriceDataBackups)
+	}
+	if err != nil {
+		return err
+	}
+	toStore, err := pricedata.NewStore(*to, *toPath, *source, cfg.PriceDataBackups)
+	if err != nil {
+		return err
+	}
+
+	points, err := fromStore.Read()
+	if err != nil {
+		return err
+	}
+	if err := toStore.Write(points); err != nil {
+		return err
+	}
+	written, err := toStore.Read()
+	if err != nil {
+		return err
+	}
+	if len(written) != len(points) {
+		return fmt.Errorf("wrote %d entries to %s but read back %d", len(points), toStore, len(written))
+	}
+	fmt.Printf("%s: migrated %d entries from %s to %s\n", *source, len(points), fromStore, toStore)
+	return nil
+}
+
+func runPriceDataProviders(args []string) error {
+	fs := flag.NewFlagSet("pricedata providers", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	from := fs.String("from", "2022-07-18", "First day of history to fetch, YYYY-MM-DD.")
+	to := fs.String("to", "2022-07-27", "Last day of history to fetch, YYYY-MM-DD.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("error parsing -from: %w", err)
+	}
+	end, err := time.Parse("2006-01-02", *to)
+	if err != nil {
+		return fmt.Errorf("error parsing -to: %w", err)
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+
+	failed := 0
+	for _, p := range cfg.PriceProviders {
+		provider, err := priceprovider.New(p)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (%s):\n", provider.Name(), p.BaseUrl)
+		if points, err := provider.History(start, end); errors.Is(err, priceprovider.ErrUnsupported) {
+			fmt.Printf("  history: not supported\n")
+		} else if err != nil {
+			failed++
+			fmt.Printf("  history: %s\n", err.Error())
+		} else if len(points) > 0 {
+			first, last := points[0], points[len(points)-1]
+			fmt.Printf("  history: %d day(s), %s open %v to %s close %v\n", len(points),
+				first.Time().Format("2006-01-02"), first.OpenPrice, last.Time().Format("2006-01-02"), last.ClosePrice)
+		} else {
+			failed++
+			fmt.Printf("  history: no prices between %s and %s\n", *from, *to)
+		}
+		if price, err := provider.Current(); err != nil {
+			failed++
+			fmt.Printf("  current: %s\n", err.Error())
+		} else {
+			fmt.Printf("  current: %.2f\n", price)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d provider call(s) failed", failed)
+	}
+	return nil
+}
+
+func printAuditReport(location string, report pricedata.AuditReport) {
+	fmt.Printf("%s (%s): %d entries from %s to %s\n", report.Source, location, report.Points,
+		time.Unix(report.First, 0).UTC().Format("2006-01-02"), time.Unix(report.Last, 0).UTC().Format("2006-01-02"))
+	for _, kind := range []string{pricedata.IssueGap, pricedata.IssueDuplicate, pricedata.IssueOutOfOrder,
+		pricedata.IssueNotMidnight, pricedata.IssueMissingPrice, pricedata.IssueOutlier} {
+		fmt.Printf("  %s: %d\n", kind, report.Count(kind))
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("  [%s] %s\n", issue.Kind, issue.Detail)
+	}
+	fmt.Println()
+}
+
+func writeRepair(cfg *config.Config, f *priceFile, repaired []pricedata.PricePoint, changes []pricedata.Change) error {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	outPath := base + ".repaired" + ext
+	changelogPath := base + ".repaired.changelog.json"
+	if cfg.PriceDataBackend == pricedata.BackendSQLite {
+		changelogPath = base + "." + f.name + ".repaired.changelog.json"
+	}
+
+	out, err := pricedata.NewStore(cfg.PriceDataBackend, outPath, f.name, cfg.PriceDataBackups)
+	if err != nil {
+		return err
+	}
+	if err := out.Write(repaired); err != nil {
+		return err
+	}
+	changelog, err := json.MarshalIndent(struct {
+		Source   string             `json:"source"`
+		Input    string             `json:"input"`
+		Output   string             `json:"output"`
+		Repaired string             `json:"repaired"`
+		Changes  []pricedata.Change `json:"changes"`
+	}{f.name, f.store.String(), out.String(), time.Now().UTC().Format(time.RFC3339), changes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding changelog: %w", err)
+	}
+	if err := os.WriteFile(changelogPath, append(changelog, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", changelogPath, err)
+	}
+	fmt.Printf("%s: wrote %d entries to %s with %d changes logged in %s\n", f.name, len(repaired), out, len(changes), changelogPath)
+	return nil
+}
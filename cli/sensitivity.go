@@ -0,0 +1,109 @@
+package main
+
+import (
+	"Mining-Profitability/pkg/calc"
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/utils"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const sensitivityUsage = `usage:
+  sensitivity [-config ../config.yaml] -x kwhPrice:0.05:0.20:7 -y bitcoinPrice:10000:60000:6 [-format json|csv] [-heatmap sensitivity.png] [calculator flags]`
+
+// runSensitivity sweeps two calculator inputs over a grid and prints percent
+// paid off, breakeven dates and strategy rankings for every cell.
+func runSensitivity(args []string) error {
+	fs := flag.NewFlagSet("sensitivity", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	x := fs.String("x", "", "First input to sweep as input:from:to:steps, one of "+strings.Join(calc.SensitivityInputs(), ", "))
+	y := fs.String("y", "", "Second input to sweep as input:from:to:steps")
+	format := fs.String("format", "json", "Print the grid as json or csv.")
+	heatmap := fs.String("heatmap", "sensitivity.png", "Write a heatmap of percent paid off here. Empty skips it.")
+
+	var request calc.RequestPayload
+	fs.StringVar(&request.StartDate, "startDate", "01/01/2022", "Specify start date of mining operation.")
+	fs.StringVar(&request.AsOfDate, "asOfDate", "", "Compute everything as of this past date (mm/dd/yyyy).")
+	fs.Float64Var(&request.KwhPrice, "kwhPrice", 0.15, "Specify price paid per kilowatt hour.")
+	fs.Float64Var(&request.Watts, "watts", 3200, "Specify watts used in total.")
+	fs.Float64Var(&request.UptimePercent, "uptimePercent", 100.0, "Specify percent uptime of your miners.")
+	fs.Float64Var(&request.FixedCosts, "fixedCosts", 6295.55, "Specify mining setup fix costs.")
+	fs.Float64Var(&request.BitcoinMined, "bitcoinMined", 0, "Specify total bitcoin mined.")
+	fs.Float64Var(&request.BitcoinPrice, "bitcoinPrice", 0, "Value coins at this price instead of the current or as-of price.")
+	fs.Float64Var(&request.HashrateTHs, "hashrate", 0, "Specify total hashrate of your miners in TH/s.")
+	fs.StringVar(&request.Currency, "currency", "", "Currency costs are entered in and results are shown in.")
+	fs.StringVar(&request.PriceSource, "priceSource", pricedata.SourceKraken, "Specify historical price source.")
+	fs.StringVar(&request.PriceField, "priceField", pricedata.FieldOpen, "Specify which daily price strategies buy at.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *x == "" || *y == "" {
+		return fmt.Errorf(sensitivityUsage)
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("format must be json or csv")
+	}
+	if request.BitcoinMined == 0 {
+		return fmt.Errorf("bitcoinMined is required")
+	}
+	sensitivityRequest := calc.SensitivityRequest{RequestPayload: request}
+	var err error
+	if sensitivityRequest.X, err = calc.ParseSensitivityAxis(*x); err != nil {
+		return err
+	}
+	if sensitivityRequest.Y, err = calc.ParseSensitivityAxis(*y); err != nil {
+		return err
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+	sources, err := pricedata.LoadSources(cfg)
+	if err != nil {
+		return fmt.Errorf("error loading price data: %w", err)
+	}
+	externalData, err := externaldata.New(cfg, sources)
+	if err != nil {
+		return err
+	}
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	calcClient := calc.New(cfg, logger)
+	grid, err := calcClient.Sensitivity(sensitivityRequest, externalData, utils.New())
+	if err != nil {
+		return err
+	}
+
+	if *format == "csv" {
+		if err := grid.WriteCSV(os.Stdout); err != nil {
+			return err
+		}
+	} else {
+		content, err := json.MarshalIndent(grid, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+	}
+
+	if *heatmap == "" {
+		return nil
+	}
+	fileName, err := calcClient.MakeHeatmap(grid)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(*fileName, *heatmap); err != nil {
+		return fmt.Errorf("error writing heatmap: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote heatmap to %s\n", *heatmap)
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"Mining-Profitability/pkg/calc"
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/utils"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const taxUsage = `usage:
+  tax [-config ../config.yaml] -minedLedger mined.csv [-sales sales.csv] [-lotMethod fifo|lifo|hifo|specific-id] [-format text|json] [calculator flags]`
+
+// runTax prints the mined coins' income by tax year next to the deductible
+// electricity and depreciation, and the gains on the recorded sales.
+func runTax(args []string) error {
+	fs := flag.NewFlagSet("tax", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	minedLedger := fs.String("minedLedger", "", "Path to a CSV of mined bitcoin by day with a date,bitcoin header.")
+	sales := fs.String("sales", "", "Path to a CSV of sales with a date,bitcoin,proceeds header and an optional lots column of receipt dates separated by semicolons.")
+	format := fs.String("format", "text", "Print the report as text or json.")
+	machinesFile := fs.String("machines", "", "Path to a CSV of machines. Replaces watts and fixedCosts.")
+	tariffFile := fs.String("tariff", "", "Path to a YAML or JSON electricity tariff to price each day's electricity with instead of kwhPrice.")
+	depreciation := fs.String("depreciation", "", "How hardware loses value to deduct it: straight-line, declining-balance or index. Nothing is deducted without it.")
+
+	var request calc.TaxRequest
+	var electricCosts float64
+	schedule := &calc.DepreciationSchedule{}
+	fs.StringVar(&request.LotMethod, "lotMethod", calc.LotFIFO, "How sales are matched to lots: "+strings.Join(calc.LotMethods(), ", ")+".")
+	fs.IntVar(&request.LongTermDays, "longTermDays", 365, "Days a lot has to be held for more than for its gain to be long term.")
+	fs.StringVar(&request.StartDate, "startDate", "01/01/2022", "Specify start date of mining operation.")
+	fs.StringVar(&request.AsOfDate, "asOfDate", "", "Stop the report on this date (mm/dd/yyyy) instead of today.")
+	fs.Float64Var(&request.KwhPrice, "kwhPrice", 0.15, "Specify price paid per kilowatt hour.")
+	fs.Float64Var(&request.Watts, "watts", 3200, "Specify watts used in total.")
+	fs.Float64Var(&request.UptimePercent, "uptimePercent", 100.0, "Specify percent uptime of your miners.")
+	fs.Float64Var(&request.FixedCosts, "fixedCosts", 6295.55, "Specify mining setup fix costs.")
+	fs.Float64Var(&electricCosts, "electricCosts", 0, "Specify total amount spent on electricity, spread evenly over the days.")
+	fs.Float64Var(&schedule.LifeYears, "lifeYears", 3, "Specify the years straight-line depreciation takes to reach the salvage value.")
+	fs.Float64Var(&schedule.SalvagePercent, "salvagePercent", 0, "Specify the percent of its cost hardware is never worth less than.")
+	fs.Float64Var(&schedule.RatePercent, "depreciationRate", 0, "Specify the yearly percent declining-balance depreciation loses.")
+	fs.StringVar(&request.Currency, "currency", "", "Currency costs are entered in and results are shown in.")
+	fs.StringVar(&request.PriceSource, "priceSource", pricedata.SourceKraken, "Specify historical price source.")
+	fs.StringVar(&request.PriceField, "priceField", pricedata.FieldClose, "Specify which daily price values the coins at receipt.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *minedLedger == "" {
+		return fmt.Errorf(taxUsage)
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("format must be text or json")
+	}
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if *machinesFile != "" && !setFlags["startDate"] {
+		// the fleet's first purchase
+		request.StartDate = ""
+	}
+
+	ledger, err := os.ReadFile(*minedLedger)
+	if err != nil {
+		return fmt.Errorf("error reading mined ledger: %w", err)
+	}
+	request.MinedLedgerCsv = string(ledger)
+	if *sales != "" {
+		content, err := os.ReadFile(*sales)
+		if err != nil {
+			return fmt.Errorf("error reading sales: %w", err)
+		}
+		request.SalesCsv = string(content)
+	}
+	if *machinesFile != "" {
+		if request.Machines, err = LoadMachines(*machinesFile); err != nil {
+			return fmt.Errorf("error reading machines: %w", err)
+		}
+	}
+	if *tariffFile != "" {
+		if request.Tariff, err = calc.LoadTariff(*tariffFile); err != nil {
+			return err
+		}
+	}
+	if electricCosts != 0 {
+		request.ElectricCosts = &electricCosts
+	}
+	if *depreciation != "" {
+		schedule.Method = *depreciation
+		request.Depreciation = schedule
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+	sources, err := pricedata.LoadSources(cfg)
+	if err != nil {
+		return fmt.Errorf("error loading price data: %w", err)
+	}
+	externalData, err := externaldata.New(cfg, sources)
+	if err != nil {
+		return err
+	}
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	report, err := calc.New(cfg, logger).Tax(request, externalData, utils.New())
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+	fmt.Printf("Tax report %s to %s in %s, coins valued at the %s %s price, sales matched %s\n", report.StartDate, report.EndDate, report.Currency, report.PriceSource, report.PriceField, report.LotMethod)
+	for _, year := range report.Years {
+		fmt.Printf("\n%d\n", year.Year)
+		fmt.Printf("  Bitcoin received: %s, income: %s\n", fmt.Sprintf("%.8f", year.BitcoinReceived), fmt.Sprintf("%.2f", year.Income))
+		fmt.Printf("  Electricity: %s, depreciation: %s, net income: %s\n", fmt.Sprintf("%.2f", year.ElectricCosts), fmt.Sprintf("%.2f", year.Depreciation), fmt.Sprintf("%.2f", year.NetIncome))
+		fmt.Printf("  Bitcoin sold: %s for %s, cost basis: %s\n", fmt.Sprintf("%.8f", year.BitcoinSold), fmt.Sprintf("%.2f", year.Proceeds), fmt.Sprintf("%.2f", year.CostBasis))
+		fmt.Printf("  Short term gains: %s, long term gains: %s\n", fmt.Sprintf("%.2f", year.ShortTermGains), fmt.Sprintf("%.2f", year.LongTermGains))
+	}
+	if len(report.Gains) > 0 {
+		fmt.Printf("\nSold       Lot        Bitcoin     Proceeds  Cost basis  Gain      Term\n")
+	}
+	for _, gain := range report.Gains {
+		term := "short"
+		if gain.LongTerm {
+			term = "long"
+		}
+		fmt.Printf("%s %s %.8f %9.2f %11.2f %9.2f %s\n", gain.SaleDate, gain.LotID, gain.Bitcoin, gain.Proceeds, gain.CostBasis, gain.Gain, term)
+	}
+	fmt.Printf("\nStill held: %s BTC with a cost basis of %s\n", fmt.Sprintf("%.8f", report.HeldBitcoin), fmt.Sprintf("%.2f", report.HeldCostBasis))
+	return nil
+}
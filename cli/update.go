@@ -0,0 +1,255 @@
+package main
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/priceprovider"
+	"Mining-Profitability/pkg/updater"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type updateOptions struct {
+	source    string
+	from      time.Time
+	to        time.Time
+	dryRun    bool
+	overwrite bool
+}
+
+func runUpdate(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	configFile := fs.String("config", "../config.yaml", "path to config file")
+	source := fs.String("source", pricedata.SourceKraken, "Configured price source to update.")
+	providers := fs.String("provider", "", "Comma separated price providers to try in order, e.g. kraken,coinbase. Defaults to every provider in priceProviders.")
+	messariApiKey := fs.String("messariApiKey", os.Getenv("MESSARI_API_KEY"), "Messari API key, defaults to $MESSARI_API_KEY")
+	from := fs.String("from", "", "First day to fetch, YYYY-MM-DD. Defaults to the day after the last stored day.")
+	to := fs.String("to", "", "Last day to fetch, YYYY-MM-DD. Defaults to yesterday (UTC), the last finished day; today's bar is never stored.")
+	dryRun := fs.Bool("dry-run", false, "Print the days that would be added or replaced without writing them.")
+	overwrite := fs.Bool("overwrite", false, "Replace stored days whose fetched prices differ. Without it only missing days are added.")
+	every := fs.Duration("every", 0, "Keep running and update again after this long, e.g. 24h. Zero runs once.")
+	currency := fs.String("currency", "", "Update this currency's exchange rates in its fxRatePaths file, from the ECB reference rates, instead of -source.")
+	network := fs.Bool("network", false, "Update the networkDataPath file with the difficulty at every retarget and halving, from mempool.space, instead of -source.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.New(*configFile)
+	if err != nil {
+		return fmt.Errorf("error getting the config: %w", err)
+	}
+	opts := updateOptions{source: *source, dryRun: *dryRun, overwrite: *overwrite}
+	if opts.from, err = parseUpdateDate(*from); err != nil {
+		return err
+	}
+	if opts.to, err = parseUpdateDate(*to); err != nil {
+		return err
+	}
+	if *network {
+		if cfg.NetworkDataPath == "" {
+			return fmt.Errorf("no networkDataPath configured")
+		}
+		provider := priceprovider.NewNetworkProvider(cfg)
+		return runUpdates(cfg.NetworkDataPath, opts, *every, func(opts updateOptions) error {
+			return runNetworkUpdateOnce(provider, cfg.NetworkDataPath, cfg.PriceDataBackups, opts)
+		})
+	}
+
+	var store pricedata.PriceStore
+	var provider priceprovider.PriceProvider
+	if *currency != "" {
+		opts.source = strings.ToUpper(*currency)
+		if store, err = pricedata.ConfiguredFXStore(cfg, *currency); err != nil {
+			return err
+		}
+		if provider, err = priceprovider.NewFXProvider(cfg, *currency); err != nil {
+			return err
+		}
+	} else {
+		if store, err = pricedata.ConfiguredStore(cfg, *source); err != nil {
+			return err
+		}
+		if *messariApiKey != "" {
+			for i := range cfg.PriceProviders {
+				if strings.EqualFold(cfg.PriceProviders[i].Name, priceprovider.Messari) {
+					cfg.PriceProviders[i].ApiKey = *messariApiKey
+				}
+			}
+		}
+		names := []string{}
+		if *providers != "" {
+			names = strings.Split(*providers, ",")
+		}
+		if provider, err = priceprovider.Configured(cfg, names); err != nil {
+			return err
+		}
+	}
+	return runUpdates(fmt.Sprint(store), opts, *every, func(opts updateOptions) error {
+		return runUpdateOnce(provider, store, opts)
+	})
+}
+
+// runUpdates runs update, which updates the file called name, once or every
+// interval when it is set.
+func runUpdates(name string, opts updateOptions, every time.Duration, update func(opts updateOptions) error) error {
+	if every <= 0 {
+		return update(opts)
+	}
+	// A failed run is logged and tried again, waiting twice as long each
+	// time up to -every, so one provider or network hiccup doesn't stop it.
+	retry := time.Minute
+	for {
+		if err := update(opts); err != nil {
+			wait := retry
+			if wait > every {
+				wait = every
+			}
+			fmt.Fprintf(os.Stderr, "error updating %s: %s, trying again in %s\n", name, err, wait)
+			time.Sleep(wait)
+			retry *= 2
+			continue
+		}
+		retry = time.Minute
+		// -from and -to only apply to the first successful run; later runs
+		// pick up wherever the store ends.
+		opts.from, opts.to = time.Time{}, time.Time{}
+		time.Sleep(every)
+	}
+}
+
+func parseUpdateDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing date %q, expected YYYY-MM-DD: %w", date, err)
+	}
+	return t, nil
+}
+
+func runUpdateOnce(provider priceprovider.PriceProvider, store pricedata.PriceStore, opts updateOptions) error {
+	// a new exchange rate file is created by its first backfill
+	stored, err := store.Read()
+	if errors.Is(err, os.ErrNotExist) {
+		stored, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// today's bar is still moving, and later runs start after the last
+	// stored day, so it would never be corrected
+	from, to := opts.from, opts.to
+	yesterday := priceprovider.Midnight(time.Now()).AddDate(0, 0, -1)
+	if to.IsZero() || to.After(yesterday) {
+		to = yesterday
+	}
+	if from.IsZero() {
+		last, ok := pricedata.NewPriceSeries(opts.source, stored).Last()
+		if !ok {
+			return fmt.Errorf("%s is empty, pass -from to backfill it", store)
+		}
+		from = priceprovider.Midnight(last.Time()).AddDate(0, 0, 1)
+	}
+	if from.After(to) {
+		fmt.Printf("%s is up to date through %s\n", store, to.Format("2006-01-02"))
+		return nil
+	}
+
+	fmt.Printf("fetching %s from %s to %s\n", provider.Name(), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	fetched, err := provider.History(from, to)
+	if err != nil {
+		return err
+	}
+
+	plan := updater.NewPlan(stored, fetched, opts.overwrite)
+	if plan.Empty() {
+		fmt.Printf("nothing to update in %s, %d fetched day(s) already stored\n", store, plan.Unchanged)
+		return nil
+	}
+	if opts.dryRun {
+		for _, line := range plan.Lines() {
+			fmt.Println(line)
+		}
+		fmt.Printf("dry run: would add %d and replace %d day(s) in %s, %d unchanged\n", len(plan.Added), len(plan.Replaced), store, plan.Unchanged)
+		return nil
+	}
+
+	// plan again under the store's lock in case it changed since it was read
+	err = store.Update(func(points []pricedata.PricePoint) ([]pricedata.PricePoint, error) {
+		plan = updater.NewPlan(points, fetched, opts.overwrite)
+		return plan.Apply(points), nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, line := range plan.Lines() {
+		fmt.Println(line)
+	}
+	fmt.Printf("added %d and replaced %d day(s) in %s, %d unchanged\n", len(plan.Added), len(plan.Replaced), store, plan.Unchanged)
+	return nil
+}
+
+// runNetworkUpdateOnce adds the retargets and halvings since the last stored
+// day to the network data file at path, backfilling the whole history when
+// it is missing. Difficulty on the current day is already final, so unlike
+// prices it runs through today.
+func runNetworkUpdateOnce(provider *priceprovider.MempoolProvider, path string, backups int, opts updateOptions) error {
+	stored := []pricedata.NetworkPoint{}
+	series, err := pricedata.LoadNetwork(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	from, to := opts.from, opts.to
+	if err == nil {
+		stored = series.Points()
+		if last, ok := series.Last(); ok && from.IsZero() {
+			from = priceprovider.Midnight(last.Time()).AddDate(0, 0, 1)
+		}
+	}
+	today := priceprovider.Midnight(time.Now())
+	if to.IsZero() || to.After(today) {
+		to = today
+	}
+	if from.After(to) {
+		fmt.Printf("%s is up to date through %s\n", path, to.Format("2006-01-02"))
+		return nil
+	}
+
+	fmt.Printf("fetching %s network data through %s\n", provider.Name(), to.Format("2006-01-02"))
+	fetched, err := provider.History(from, to)
+	if err != nil {
+		return err
+	}
+
+	plan := updater.NewNetworkPlan(stored, fetched, opts.overwrite)
+	if plan.Empty() {
+		fmt.Printf("nothing to update in %s, %d fetched day(s) already stored\n", path, plan.Unchanged)
+		return nil
+	}
+	if opts.dryRun {
+		for _, line := range plan.Lines() {
+			fmt.Println(line)
+		}
+		fmt.Printf("dry run: would add %d and replace %d day(s) in %s, %d unchanged\n", len(plan.Added), len(plan.Replaced), path, plan.Unchanged)
+		return nil
+	}
+
+	err = pricedata.UpdateNetwork(path, backups, func(points []pricedata.NetworkPoint) ([]pricedata.NetworkPoint, error) {
+		plan = updater.NewNetworkPlan(points, fetched, opts.overwrite)
+		return plan.Apply(points), nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, line := range plan.Lines() {
+		fmt.Println(line)
+	}
+	fmt.Printf("added %d and replaced %d day(s) in %s, %d unchanged\n", len(plan.Added), len(plan.Replaced), path, plan.Unchanged)
+	return nil
+}
@@ -3,9 +3,11 @@ module Mining-Profitability
 go 1.17
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/sirupsen/logrus v1.8.1
 	github.com/tidwall/gjson v1.14.0
 	github.com/tidwall/sjson v1.2.4
+	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654
 	gonum.org/v1/plot v0.11.0
 	gopkg.in/yaml.v2 v2.2.8
 )
@@ -22,7 +24,6 @@ require (
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
 	golang.org/x/image v0.0.0-20220302094943-723b81ca9867 // indirect
-	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
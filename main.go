@@ -5,7 +5,9 @@ import (
 	"Mining-Profitability/pkg/applog"
 	"Mining-Profitability/pkg/config"
 	"Mining-Profitability/pkg/miningprofitability/imagedownload"
+	"Mining-Profitability/pkg/miningprofitability/sensitivity"
 	"Mining-Profitability/pkg/miningprofitability/statsgenerator"
+	"Mining-Profitability/pkg/miningprofitability/tax"
 	"context"
 	"flag"
 	"log"
@@ -40,6 +42,8 @@ func main() {
 
 	router.Handle("/chart", imagedownload.NewImageHandler(appContext))
 	router.Handle("/data", statsgenerator.NewDataHandler(appContext))
+	router.Handle("/sensitivity", sensitivity.NewSensitivityHandler(appContext))
+	router.Handle("/tax", tax.NewTaxHandler(appContext))
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGKILL)
@@ -4,8 +4,12 @@ import (
 	"Mining-Profitability/pkg/calc"
 	"Mining-Profitability/pkg/config"
 	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
 	"Mining-Profitability/pkg/utils"
 	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -16,20 +20,57 @@ type AppContext struct {
 	Utils        utils.Interface
 	ExternalData externaldata.Interface
 	Ctx          context.Context
+	priceSources atomic.Value // *pricedata.Sources
 }
 
 func New(cfg *config.Config, logger *logrus.Logger) (*AppContext, context.CancelFunc, error) {
 	logger.Debug("setting up context")
+	priceSources, err := pricedata.LoadSources(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading price data: %w", err)
+	}
+	logger.Debugf("loaded price sources %v, version %s", priceSources.Names(), priceSources.Version)
+
+	pollInterval := time.Duration(0)
+	if cfg.PriceDataPollInterval != "" {
+		pollInterval, err = time.ParseDuration(cfg.PriceDataPollInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing priceDataPollInterval: %w", err)
+		}
+	}
+
 	calc := calc.New(cfg, logger)
-	externalData := externaldata.New(cfg)
+	externalData, err := externaldata.New(cfg, priceSources)
+	if err != nil {
+		return nil, nil, err
+	}
 	utils := utils.New()
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &AppContext{
+	appContext := &AppContext{
 		Logger:       logger,
 		Calc:         calc,
 		Utils:        utils,
 		ExternalData: externalData,
 		Ctx:          ctx,
-	}, cancel, nil
+	}
+	appContext.priceSources.Store(priceSources)
+
+	if pollInterval > 0 {
+		go appContext.watchPriceData(ctx, cfg, pollInterval)
+	}
+
+	return appContext, cancel, nil
+}
+
+// PriceSources returns the price data currently loaded.
+func (a *AppContext) PriceSources() *pricedata.Sources {
+	return a.priceSources.Load().(*pricedata.Sources)
+}
+
+// Snapshot returns the external data client bound to the price data loaded
+// right now. Handlers take one snapshot per request so a reload in the middle
+// of it can't mix old and new prices.
+func (a *AppContext) Snapshot() externaldata.Interface {
+	return a.ExternalData.WithPriceSources(a.PriceSources())
 }
@@ -0,0 +1,85 @@
+package appcontext
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"context"
+	"os"
+	"time"
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statPaths(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{info.ModTime(), info.Size()}
+		}
+	}
+	return stamps
+}
+
+func stampsChanged(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return true
+		}
+	}
+	return false
+}
+
+// watchPriceData polls the configured price files until ctx is done. When a
+// file's mtime or size changes and its checksum no longer matches the loaded
+// version, it reloads every source, validates them against what is loaded
+// and swaps them in. A reload that fails leaves the current data in place
+// and is tried again on the next tick, e.g. once a half-written file is done.
+func (a *AppContext) watchPriceData(ctx context.Context, cfg *config.Config, interval time.Duration) {
+	paths := pricedata.ConfiguredPaths(cfg)
+	stamps := statPaths(paths)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := statPaths(paths)
+		if !stampsChanged(stamps, current) {
+			continue
+		}
+
+		loaded := a.PriceSources()
+		version, err := pricedata.Checksum(paths)
+		if err != nil {
+			a.Logger.WithError(err).Error("error checking price data for changes")
+			continue
+		}
+		if version == loaded.Version {
+			stamps = current
+			continue
+		}
+
+		reloaded, err := pricedata.LoadSources(cfg)
+		if err != nil {
+			a.Logger.WithError(err).Error("error reloading price data, keeping version " + loaded.Version)
+			continue
+		}
+		if err := reloaded.Validate(loaded); err != nil {
+			a.Logger.WithError(err).Error("reloaded price data failed validation, keeping version " + loaded.Version)
+			continue
+		}
+		stamps = current
+		a.priceSources.Store(reloaded)
+		a.Logger.Infof("reloaded price data version %s through %s", reloaded.Version, reloaded.LastDate().Format("01/02/2006"))
+	}
+}
@@ -3,13 +3,14 @@ package calc
 import (
 	"Mining-Profitability/pkg/config"
 	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
 	"Mining-Profitability/pkg/utils"
 	"fmt"
 	"image/color"
 	"math"
 	"os"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -35,46 +36,169 @@ type RequestPayload struct {
 	MessariApiKey      string   `json:"messariApiKey"`
 	HideBitcoinOnGraph bool     `json:"hideBitcoinOnGraph"`
 	ShowStrategyData   bool     `json:"showStrategyData"`
+	PriceSource        string   `json:"priceSource"`
+	PriceField         string   `json:"priceField"`
+	// AsOfDate, when set, computes the whole report as it stood on that day:
+	// coins are valued at that day's price and days, costs and strategies
+	// stop there. BitcoinMined should then be the total mined by that day.
+	AsOfDate string `json:"asOfDate"`
+	// EndDate, when set, is the day a retired operation stopped. Days,
+	// costs, mining and strategies stop there, the coins are still valued
+	// at the current or as-of price, and the report gives the realized
+	// profit instead of a breakeven estimate.
+	EndDate string `json:"endDate"`
+	// Currency is what kwhPrice, fixedCosts and electricCosts are given in,
+	// e.g. EUR. Strategy purchases and every amount in the report use it too.
+	// Defaults to the configured currency.
+	Currency string `json:"currency"`
+	// MinedLedger lists BTC mined per day or per payout. When it or
+	// MinedLedgerCsv (the same as date,bitcoin CSV text) is given, the mined
+	// total comes from it and the chart shows the real cumulative curve.
+	MinedLedger    []MinedEntry `json:"minedLedger"`
+	MinedLedgerCsv string       `json:"minedLedgerCsv"`
+	// HashrateTHs is the miners' combined hashrate in TH/s. When set, the
+	// report works out what it should have mined at each day's network
+	// difficulty and compares that with what was actually mined.
+	HashrateTHs float64 `json:"hashrateTHs"`
+	// DifficultyGrowthPercent and PriceGrowthPercent override the yearly
+	// growth the breakeven projection assumes. ProjectedPrice is the price
+	// it starts from instead of the current one.
+	DifficultyGrowthPercent *float64 `json:"difficultyGrowthPercent"`
+	PriceGrowthPercent      *float64 `json:"priceGrowthPercent"`
+	ProjectedPrice          float64  `json:"projectedPrice"`
+	// Simulation, when set, adds a Monte Carlo breakeven forecast over
+	// simulated price paths to the report and a fan chart to the image.
+	Simulation *SimulationRequest `json:"simulation"`
+	// BitcoinPrice, when set, is the price in Currency the mined coins are
+	// valued at instead of the current or as-of price.
+	BitcoinPrice float64 `json:"bitcoinPrice"`
+	// Machines, when given, replace watts, fixedCosts and hashrateTHs with
+	// a fleet bought, run and sold machine by machine. startDate defaults to
+	// the first purchase.
+	Machines []Machine `json:"machines"`
+	// SalePrice is what hardware already sold brought in, taken off the
+	// costs. HardwareValue is what the hardware still held could be sold
+	// for; without it Depreciation works that out from what it cost, unless
+	// SalePrice says it was sold.
+	SalePrice     float64               `json:"salePrice"`
+	HardwareValue float64               `json:"hardwareValue"`
+	Depreciation  *DepreciationSchedule `json:"depreciation"`
+	// Tariff prices the electricity day by day instead of kwhPrice.
+	// TariffName picks one from tariffPaths in the config instead.
+	Tariff     *Tariff `json:"tariff"`
+	TariffName string  `json:"tariffName"`
+	// HeatReuse credits the heating the miners' heat replaced against the
+	// electricity, day by day.
+	HeatReuse *HeatReuse `json:"heatReuse"`
+	// Pool is the pool and payout scheme the coins were mined with. Its
+	// withdrawal fees are taken off bitcoinMined.
+	Pool *PoolPayout `json:"pool"`
+	// Sales are mined coins sold or spent, here or as date,bitcoin,proceeds
+	// CSV text in SalesCsv. They are taken out of what the mined ledger had
+	// mined by each sale, or out of BitcoinMined without one, and each
+	// strategy sells enough on the same days to get the same cash back.
+	Sales    []CoinSale `json:"sales"`
+	SalesCsv string     `json:"salesCsv"`
+	// Strategies names the strategies to compare mining with, all of the
+	// registered ones by default.
+	Strategies []string `json:"strategies"`
 }
 
 type ReturnPayload struct {
-	BitcoinMined               float64            `json:"bitcoinMined"`
-	ElectricCosts              float64            `json:"electicCosts"`
-	FixedCosts                 float64            `json:"fixedCosts"`
-	BitcoinPrice               float64            `json:"bitcoinPrice"`
-	DaysSinceStarted           float64            `json:"daysSinceStart"`
-	AverageCoinsPerDay         float64            `json:"averageCoinsPerDay"`
-	DollarinosEarned           float64            `json:"dollarinosEarned"`
-	PercentPaidOff             float64            `json:"percentPaidOff"`
-	BreakevenPriceIncrease     float64            `json:"breakevenPriceIncrease"`
-	BreakevenPrice             float64            `json:"breakevenPrice"`
-	DaysUntilBreakeven         float64            `json:"daysUntilBreakeven"`
-	TotalMiningDaysToBreakEven float64            `json:"totalMiningDaysToBreakEven"`
-	ExpectedBreakevenDate      string             `json:"expectedBreakevenDate"`
-	DailyElectricCost          float64            `json:"dailyElectricCost"`
-	TotalDollarsSpent          float64            `json:"totalDollarsSpent"`
-	DcaBitcoin                 float64            `json:"dcaBitcoin"`
-	DcaData                    []float64          `json:"dcaData"`
-	AhBitcoin                  float64            `json:"ahBitcoin"`
-	AhData                     []float64          `json:"ahData"`
-	AntiHomeMinerBitcoin       float64            `json:"antiHomeMinerBitcoin"`
-	AntiHomeMinerData          []float64          `json:"antiHomeMinerData"`
-	Rankings                   map[string]float64 `json:"rankings"`
+	BitcoinMined               float64           `json:"bitcoinMined"`
+	ElectricCosts              float64           `json:"electicCosts"`
+	FixedCosts                 float64           `json:"fixedCosts"`
+	BitcoinPrice               float64           `json:"bitcoinPrice"`
+	DaysSinceStarted           float64           `json:"daysSinceStart"`
+	AverageCoinsPerDay         float64           `json:"averageCoinsPerDay"`
+	DollarinosEarned           float64           `json:"dollarinosEarned"`
+	PercentPaidOff             float64           `json:"percentPaidOff"`
+	BreakevenPriceIncrease     float64           `json:"breakevenPriceIncrease"`
+	BreakevenPrice             float64           `json:"breakevenPrice"`
+	DaysUntilBreakeven         float64           `json:"daysUntilBreakeven"`
+	TotalMiningDaysToBreakEven float64           `json:"totalMiningDaysToBreakEven"`
+	ExpectedBreakevenDate      string            `json:"expectedBreakevenDate"`
+	DailyElectricCost          float64           `json:"dailyElectricCost"`
+	TotalDollarsSpent          float64           `json:"totalDollarsSpent"`
+	Strategies                 []StrategyResult  `json:"strategies"`
+	Rankings                   []StrategyRanking `json:"rankings"`
+	PriceSource                string            `json:"priceSource"`
+	PriceField                 string            `json:"priceField"`
+	PriceDataVersion           string            `json:"priceDataVersion"`
+	PriceDataLastDate          string            `json:"priceDataLastDate"`
+	AsOfDate                   string            `json:"asOfDate"`
+	Currency                   string            `json:"currency"`
+	FxRate                     float64           `json:"fxRate"`
+	MinedData                  []float64         `json:"minedData"`
+	RankingsByDay              []DailyRanking    `json:"rankingsByDay,omitempty"`
+	ExpectedBitcoinMined       float64           `json:"expectedBitcoinMined,omitempty"`
+	// MinedVsExpected is how far actual mining was above (positive) or
+	// below (negative) expected, in percent: pool luck plus fee leakage.
+	MinedVsExpected float64   `json:"minedVsExpected,omitempty"`
+	ExpectedData    []float64 `json:"expectedData,omitempty"`
+	// NetworkDataStale is set when the network difficulty data ends too long
+	// before the end date to use. Expected mining and the projection are then
+	// left out instead of worked out from a stale difficulty.
+	NetworkDataStale bool `json:"networkDataStale,omitempty"`
+	// Projection walks forward through halvings and difficulty growth to a
+	// breakeven date. DaysUntilBreakeven and ExpectedBreakevenDate above
+	// assume the average coins per day and today's price never change.
+	Projection *Projection `json:"projection,omitempty"`
+	Simulation *Simulation `json:"simulation,omitempty"`
+	// Fleet is the machines' cash flows when machines were given. FixedCosts
+	// above is then what they were bought for less what they were sold for.
+	Fleet     *Fleet  `json:"fleet,omitempty"`
+	SalePrice float64 `json:"salePrice,omitempty"`
+	// LiquidationValue is what the hardware still held could be sold for on
+	// the as-of date. PercentPaidOffWithHardware counts it alongside the
+	// mined coins.
+	LiquidationValue           float64 `json:"liquidationValue,omitempty"`
+	PercentPaidOffWithHardware float64 `json:"percentPaidOffWithHardware,omitempty"`
+	// Realized replaces the breakeven estimates for an operation given an
+	// end date.
+	Realized *RealizedPnL `json:"realized,omitempty"`
+	// Tariff names the tariff the electricity was priced with, and
+	// ElectricBills breaks its cost down by month.
+	Tariff        string        `json:"tariff,omitempty"`
+	ElectricBills []MonthlyBill `json:"electricBills,omitempty"`
+	// HeatReuse is what the heat saved. ElectricCosts above is then net of
+	// the credit.
+	HeatReuse *HeatCredit `json:"heatReuse,omitempty"`
+	// Pool splits the mined coins into gross and net of the pool's fees.
+	// BitcoinMined above is then the net.
+	Pool *PoolReport `json:"pool,omitempty"`
+	// Sales is the bitcoin held and the cash after the sales. The
+	// strategies' bitcoin above is then also net of what they sold.
+	Sales *SalesReport `json:"sales,omitempty"`
 }
 
 type Client struct {
-	PriceDataKrakenPath   string
-	PriceDataCoinbasePath string
-	DataPlotFileName      string
-	Logger                *logrus.Logger
+	PriceDataKrakenPath     string
+	PriceDataCoinbasePath   string
+	DataPlotFileName        string
+	Currency                string
+	DifficultyGrowthPercent float64
+	PriceGrowthPercent      float64
+	ProjectionDays          int
+	TariffPaths             map[string]string
+	Logger                  *logrus.Logger
 }
 
 func New(cfg *config.Config, logger *logrus.Logger) *Client {
+	projectionDays := cfg.ProjectionDays
+	if projectionDays <= 0 {
+		projectionDays = defaultProjectionDays
+	}
 	return &Client{
-		PriceDataKrakenPath:   cfg.PriceDataKrakenPath,   // "PriceDataKraken.json",
-		PriceDataCoinbasePath: cfg.PriceDataCoinbasePath, // "PriceDataCoinbase.json",
-		DataPlotFileName:      cfg.DataPlotFileName,      // "points.png",
-		Logger:                logger,
+		PriceDataKrakenPath:     cfg.PriceDataKrakenPath,   // "PriceDataKraken.json",
+		PriceDataCoinbasePath:   cfg.PriceDataCoinbasePath, // "PriceDataCoinbase.json",
+		DataPlotFileName:        cfg.DataPlotFileName,      // "points.png",
+		Currency:                cfg.Currency,
+		DifficultyGrowthPercent: cfg.ProjectionDifficultyGrowthPercent,
+		PriceGrowthPercent:      cfg.ProjectionPriceGrowthPercent,
+		ProjectionDays:          projectionDays,
+		TariffPaths:             cfg.TariffPaths,
+		Logger:                  logger,
 	}
 }
 
@@ -84,88 +208,385 @@ type Interface interface {
 	AverageCoinsPerDay(days, coins float64) float64
 	DollarinosEarned(coins, price float64) float64
 	ElectricCosts(kwhPrice, uptimePercentage, uptimeDays, watts float64) float64
-	PercentPaidOff(dollarinosEarned, fixedCosts, variableCosts float64) float64
+	PercentPaidOff(dollarinosEarned, fixedCosts, variableCosts, salePrice float64) float64
 	DaysSinceStart(startDate string) (*float64, error)
+	DaysBetween(startDate string, end time.Time) (*float64, error)
 	DaysSinceStartUnixTimestamp(startDate string) (*float64, error)
 	BreakEvenPrice(percentPaidOff, bitcoinPrice float64) float64
 	DaysUntilBreakeven(daysSinceStart, percentPaidOff float64) float64
 	DateFromDaysNow(days float64) (string, error)
-	AmericanHodlSlamBuy(dollarsAvailable, openPrice float64, numberDays int) ([]float64, float64)
-	DailyDCABuy(dollarsAvialble, daysSinceStart float64, priceData []float64) ([]float64, float64)
-	AntiHomeMiner(fixedCosts, electricCosts, daysSinceStart float64, priceData []float64) ([]float64, float64)
+	DateFromDaysAfter(from time.Time, days float64) (string, error)
 	CompareData() error
-	CompareStrategies(bitcoinMined float64, m map[float64]string) map[string]float64
-	MakeMinedBitcoinData(ahData []float64, minedBitcoin float64) []float64
+	CompareStrategies(bitcoinMined float64, rankings []StrategyResult) []StrategyRanking
+	MakeMinedBitcoinData(priceData []float64, minedBitcoin float64) []float64
+	ExpectedMinedCoins(network *pricedata.NetworkSeries, hashrateTHs, uptimePercent float64, start, end time.Time) (float64, error)
+	Project(assumptions ProjectionAssumptions) Projection
+	Simulate(request SimulationRequest, assumptions ProjectionAssumptions, history []pricedata.PricePoint, field string) (*Simulation, error)
+	Sensitivity(request SensitivityRequest, externalData externaldata.Interface, utils utils.Interface) (*Sensitivity, error)
+	Tax(request TaxRequest, externalData externaldata.Interface, utils utils.Interface) (*TaxReport, error)
+	MakeHeatmap(s *Sensitivity) (*string, error)
 }
 
 func (c *Client) GenerateStats(requestPayload RequestPayload, externalData externaldata.Interface, utils utils.Interface) (*ReturnPayload, error) {
 	returnPayload := &ReturnPayload{}
-	price, err := externalData.GetBitcoinPrice()
+	asOf := time.Now()
+	var err error
+	if requestPayload.AsOfDate != "" {
+		asOf, err = utils.ParseDate(requestPayload.AsOfDate)
+		if err != nil {
+			c.Logger.Error("error parsing asOfDate: %w", err)
+			return nil, fmt.Errorf("error parsing asOfDate: %w", err)
+		}
+		if asOf.After(time.Now()) {
+			return nil, fmt.Errorf("asOfDate %s is in the future", requestPayload.AsOfDate)
+		}
+		if requestPayload.SlushToken != nil {
+			return nil, fmt.Errorf("asOfDate can't be used with slushToken, slush only reports the current total so pass bitcoinMined as of that date instead")
+		}
+	}
+	(*returnPayload).AsOfDate = asOf.Format("01/02/2006")
+	end := asOf
+	if requestPayload.EndDate != "" {
+		end, err = utils.ParseDate(requestPayload.EndDate)
+		if err != nil {
+			c.Logger.Error("error parsing endDate: %w", err)
+			return nil, fmt.Errorf("error parsing endDate: %w", err)
+		}
+		if end.After(asOf) {
+			return nil, fmt.Errorf("endDate %s is after %s", requestPayload.EndDate, (*returnPayload).AsOfDate)
+		}
+		if requestPayload.Simulation != nil {
+			return nil, fmt.Errorf("simulation can't be used with endDate, an ended operation has nothing left to forecast")
+		}
+	}
+	if len(requestPayload.Machines) > 0 && requestPayload.StartDate == "" {
+		requestPayload.StartDate, err = FleetStartDate(requestPayload.Machines)
+		if err != nil {
+			c.Logger.Error("error with FleetStartDate: %w", err)
+			return nil, fmt.Errorf("error with FleetStartDate: %w", err)
+		}
+	}
+
+	currency := requestPayload.Currency
+	if currency == "" {
+		currency = c.Currency
+	}
+	if currency == "" {
+		currency = pricedata.BaseCurrency
+	}
+	(*returnPayload).Currency = strings.ToUpper(currency)
+	(*returnPayload).FxRate, err = externalData.GetFXRate(currency, asOf)
+	if err != nil {
+		c.Logger.Error("error getting exchange rate: %w", err)
+		return nil, fmt.Errorf("error getting exchange rate: %w", err)
+	}
+
+	var price *float64
+	if requestPayload.BitcoinPrice > 0 {
+		price = &requestPayload.BitcoinPrice
+	} else if requestPayload.AsOfDate != "" {
+		price, err = externalData.GetBitcoinPriceOnDate(requestPayload.PriceSource, currency, requestPayload.PriceField, asOf)
+	} else {
+		price, err = externalData.GetBitcoinPrice()
+		if err == nil {
+			*price *= (*returnPayload).FxRate
+		}
+	}
 	if err != nil {
 		c.Logger.Error("error getting bitcoin price: %w", err)
 		return nil, fmt.Errorf("error getting bitcoin price: %w", err)
 	}
 	(*returnPayload).BitcoinPrice = *price
-	daysSinceStarted, err := c.DaysSinceStart(requestPayload.StartDate)
+	daysSinceStarted, err := c.DaysBetween(requestPayload.StartDate, end)
 	if err != nil {
 		c.Logger.Error("error calculating days since start: %w", err)
 		return nil, fmt.Errorf("error calculating days since start: %w", err)
 	}
+	if *daysSinceStarted <= 0 {
+		return nil, fmt.Errorf("startDate %s must be before %s", requestPayload.StartDate, end.Format("01/02/2006"))
+	}
 	(*returnPayload).DaysSinceStarted = *daysSinceStarted
-	if requestPayload.SlushToken != nil {
+	ledger, err := requestPayload.Ledger()
+	if err != nil {
+		c.Logger.Error("error reading mined ledger: %w", err)
+		return nil, fmt.Errorf("error reading mined ledger: %w", err)
+	}
+	if len(ledger) > 0 {
+		startTime, err := utils.ParseDate(requestPayload.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("error with ParseDate: %w", err)
+		}
+		_, requestPayload.BitcoinMined, err = CumulativeMined(ledger, []time.Time{startTime, end})
+		if err != nil {
+			c.Logger.Error("error with CumulativeMined: %w", err)
+			return nil, fmt.Errorf("error with CumulativeMined: %w", err)
+		}
+	} else if requestPayload.SlushToken != nil {
 		requestPayload.BitcoinMined, err = externalData.GetUserMinedCoinsTotal(*requestPayload.SlushToken)
 		if err != nil {
 			c.Logger.Error("Error GetUseRMinedCoinsTotal: %w\n", err)
 			return nil, fmt.Errorf("error GetUseRMinedCoinsTotal: %w", err)
 		}
 	}
+	if requestPayload.Pool != nil {
+		(*returnPayload).Pool, err = requestPayload.Pool.Report(requestPayload.BitcoinMined, ledgerThrough(ledger, end))
+		if err != nil {
+			c.Logger.Error("error with pool: %w", err)
+			return nil, fmt.Errorf("error with pool: %w", err)
+		}
+		requestPayload.BitcoinMined = (*returnPayload).Pool.NetBitcoin
+	}
 	(*returnPayload).BitcoinMined = requestPayload.BitcoinMined
 	(*returnPayload).AverageCoinsPerDay = c.AverageCoinsPerDay((*returnPayload).DaysSinceStarted, returnPayload.BitcoinMined)
 	(*returnPayload).DollarinosEarned = c.DollarinosEarned((*returnPayload).BitcoinMined, (*returnPayload).BitcoinPrice)
 
-	if requestPayload.ElectricCosts == nil || *requestPayload.ElectricCosts == 0 {
+	tariff, err := c.requestTariff(requestPayload)
+	if err != nil {
+		c.Logger.Error("error with tariff: %w", err)
+		return nil, fmt.Errorf("error with tariff: %w", err)
+	}
+	if tariff != nil && requestPayload.ElectricCosts != nil && *requestPayload.ElectricCosts != 0 {
+		return nil, fmt.Errorf("give either a tariff or electicCosts, not both")
+	}
+	if len(requestPayload.Machines) > 0 && requestPayload.SalePrice != 0 {
+		// a fleet's resales are already taken off its costs
+		return nil, fmt.Errorf("give the machines' resalePrice or salePrice, not both")
+	}
+	if len(requestPayload.Machines) > 0 {
+		startTime, err := utils.ParseDate(requestPayload.StartDate)
+		if err != nil {
+			c.Logger.Error("error with ParseDate: %w", err)
+			return nil, fmt.Errorf("error with ParseDate: %w", err)
+		}
+		(*returnPayload).Fleet, err = FleetSchedule(requestPayload.Machines, startTime, end, requestPayload.KwhPrice, requestPayload.UptimePercent, requestPayload.ElectricCosts)
+		if err != nil {
+			c.Logger.Error("error with FleetSchedule: %w", err)
+			return nil, fmt.Errorf("error with FleetSchedule: %w", err)
+		}
+		if tariff != nil {
+			(*returnPayload).ElectricBills, err = (*returnPayload).Fleet.ApplyTariff(tariff, startTime, requestPayload.UptimePercent)
+			if err != nil {
+				c.Logger.Error("error with ApplyTariff: %w", err)
+				return nil, fmt.Errorf("error with ApplyTariff: %w", err)
+			}
+			(*returnPayload).Tariff = tariff.Name
+		}
+		requestPayload.ElectricCosts = &(*returnPayload).Fleet.ElectricCosts
+		requestPayload.FixedCosts = (*returnPayload).Fleet.Purchases - (*returnPayload).Fleet.Resales
+	} else if tariff != nil {
+		startTime, err := utils.ParseDate(requestPayload.StartDate)
+		if err != nil {
+			c.Logger.Error("error with ParseDate: %w", err)
+			return nil, fmt.Errorf("error with ParseDate: %w", err)
+		}
+		electricCost, bills, err := tariff.Bill(startTime, end, requestPayload.Watts, requestPayload.UptimePercent)
+		if err != nil {
+			c.Logger.Error("error with Bill: %w", err)
+			return nil, fmt.Errorf("error with Bill: %w", err)
+		}
+		requestPayload.ElectricCosts = &electricCost
+		(*returnPayload).Tariff, (*returnPayload).ElectricBills = tariff.Name, bills
+	} else if requestPayload.ElectricCosts == nil || *requestPayload.ElectricCosts == 0 {
 		electicCost := c.ElectricCosts(requestPayload.KwhPrice, requestPayload.UptimePercent, (*returnPayload).DaysSinceStarted, requestPayload.Watts)
 		requestPayload.ElectricCosts = &electicCost
 	}
+	if requestPayload.HeatReuse != nil {
+		startTime, err := utils.ParseDate(requestPayload.StartDate)
+		if err != nil {
+			c.Logger.Error("error with ParseDate: %w", err)
+			return nil, fmt.Errorf("error with ParseDate: %w", err)
+		}
+		(*returnPayload).HeatReuse, err = ApplyHeatReuse(*requestPayload.HeatReuse, (*returnPayload).Fleet, tariff, startTime, end, requestPayload.Watts, requestPayload.UptimePercent, *requestPayload.ElectricCosts)
+		if err != nil {
+			c.Logger.Error("error with ApplyHeatReuse: %w", err)
+			return nil, fmt.Errorf("error with ApplyHeatReuse: %w", err)
+		}
+		netElectricCost := *requestPayload.ElectricCosts - (*returnPayload).HeatReuse.Credit
+		requestPayload.ElectricCosts = &netElectricCost
+	}
 
 	(*returnPayload).ElectricCosts = *requestPayload.ElectricCosts
 	(*returnPayload).FixedCosts = requestPayload.FixedCosts
-	(*returnPayload).PercentPaidOff = c.PercentPaidOff((*returnPayload).DollarinosEarned, (*returnPayload).FixedCosts, (*returnPayload).ElectricCosts)
+	(*returnPayload).SalePrice = requestPayload.SalePrice
+	(*returnPayload).PercentPaidOff = c.PercentPaidOff((*returnPayload).DollarinosEarned, (*returnPayload).FixedCosts, (*returnPayload).ElectricCosts, (*returnPayload).SalePrice)
 	(*returnPayload).BreakevenPriceIncrease = ((100 / (*returnPayload).PercentPaidOff) - 1) * 100
 	(*returnPayload).BreakevenPrice = c.BreakEvenPrice((*returnPayload).PercentPaidOff, (*returnPayload).BitcoinPrice)
-	(*returnPayload).DaysUntilBreakeven = c.DaysUntilBreakeven((*returnPayload).DaysSinceStarted, (*returnPayload).PercentPaidOff)
-	(*returnPayload).TotalMiningDaysToBreakEven = (*returnPayload).DaysUntilBreakeven + (*returnPayload).DaysSinceStarted
-	(*returnPayload).ExpectedBreakevenDate, err = c.DateFromDaysNow((*returnPayload).DaysUntilBreakeven)
-	if err != nil {
-		c.Logger.Error("error with DateFromDaysNow: %w", err)
-		return nil, fmt.Errorf("error with DateFromDaysNow: %w", err)
+	if requestPayload.EndDate == "" {
+		(*returnPayload).DaysUntilBreakeven = c.DaysUntilBreakeven((*returnPayload).DaysSinceStarted, (*returnPayload).PercentPaidOff)
+		(*returnPayload).TotalMiningDaysToBreakEven = (*returnPayload).DaysUntilBreakeven + (*returnPayload).DaysSinceStarted
+		(*returnPayload).ExpectedBreakevenDate, err = c.DateFromDaysAfter(asOf, (*returnPayload).DaysUntilBreakeven)
+		if err != nil {
+			c.Logger.Error("error with DateFromDaysAfter: %w", err)
+			return nil, fmt.Errorf("error with DateFromDaysAfter: %w", err)
+		}
 	}
 
 	(*returnPayload).DailyElectricCost = (*returnPayload).ElectricCosts / (*returnPayload).DaysSinceStarted
-	unixTimeStampStart, err := utils.DateToUnixTimestamp(requestPayload.StartDate)
+	startTime, err := utils.ParseDate(requestPayload.StartDate)
 	if err != nil {
-		c.Logger.Error("error with DateToUnixTimestamp: %w", err)
-		return nil, fmt.Errorf("error with DateToUnixTimestamp: %w", err)
+		c.Logger.Error("error with ParseDate: %w", err)
+		return nil, fmt.Errorf("error with ParseDate: %w", err)
 	}
-	priceData := externalData.GetPriceDataFromDateRange(unixTimeStampStart)
-	(*returnPayload).TotalDollarsSpent = (*returnPayload).ElectricCosts + (*returnPayload).FixedCosts
-	unixDaysSinceStart, err := utils.RegularDateToUnix(requestPayload.StartDate)
+	priceSeries, err := externalData.GetPriceSeries(requestPayload.PriceSource, currency)
 	if err != nil {
-		c.Logger.Error("error with RegularDateToUnix: %w", err)
-		return nil, fmt.Errorf("error with RegularDateToUnix: %w", err)
+		c.Logger.Error("error with GetPriceSeries: %w", err)
+		return nil, fmt.Errorf("error with GetPriceSeries: %w", err)
+	}
+	(*returnPayload).PriceSource = priceSeries.Name
+	(*returnPayload).PriceDataVersion = externalData.GetPriceDataVersion()
+	lastPrice, _ := priceSeries.Last()
+	(*returnPayload).PriceDataLastDate = lastPrice.Time().Format("01/02/2006")
+	(*returnPayload).PriceField = requestPayload.PriceField
+	if (*returnPayload).PriceField == "" {
+		(*returnPayload).PriceField = pricedata.FieldOpen
+	}
+	priceData, err := externalData.GetPriceDataFromDateRange(priceSeries.Name, currency, (*returnPayload).PriceField, startTime, end)
+	if err != nil {
+		c.Logger.Error("error with GetPriceDataFromDateRange: %w", err)
+		return nil, fmt.Errorf("error with GetPriceDataFromDateRange: %w", err)
+	}
+	(*returnPayload).TotalDollarsSpent = (*returnPayload).ElectricCosts + (*returnPayload).FixedCosts - (*returnPayload).SalePrice
+	if (*returnPayload).HeatReuse != nil {
+		(*returnPayload).HeatReuse.CostWithCredit = (*returnPayload).TotalDollarsSpent
+		(*returnPayload).HeatReuse.CostWithoutCredit = (*returnPayload).TotalDollarsSpent + (*returnPayload).HeatReuse.Credit
+	}
+	(*returnPayload).LiquidationValue = requestPayload.HardwareValue
+	// Hardware sold for salePrice is already taken off the costs, so it
+	// isn't valued again as still held.
+	if requestPayload.HardwareValue == 0 && requestPayload.Depreciation != nil && requestPayload.SalePrice == 0 {
+		(*returnPayload).LiquidationValue, err = LiquidationValue(*requestPayload.Depreciation, requestPayload.Machines, requestPayload.FixedCosts, startTime, asOf)
+		if err != nil {
+			c.Logger.Error("error with LiquidationValue: %w", err)
+			return nil, fmt.Errorf("error with LiquidationValue: %w", err)
+		}
+	}
+	if (*returnPayload).LiquidationValue > 0 {
+		(*returnPayload).PercentPaidOffWithHardware = c.PercentPaidOff((*returnPayload).DollarinosEarned+(*returnPayload).LiquidationValue, (*returnPayload).FixedCosts, (*returnPayload).ElectricCosts, (*returnPayload).SalePrice)
+	}
+	unixDaysSinceStart, err := utils.DaysBetweenDates(requestPayload.StartDate, end)
+	if err != nil {
+		c.Logger.Error("error with DaysBetweenDates: %w", err)
+		return nil, fmt.Errorf("error with DaysBetweenDates: %w", err)
 	}
 
-	(*returnPayload).DcaData, (*returnPayload).DcaBitcoin = c.DailyDCABuy((*returnPayload).TotalDollarsSpent, unixDaysSinceStart, priceData)
-	(*returnPayload).AhData, (*returnPayload).AhBitcoin = c.AmericanHodlSlamBuy((*returnPayload).TotalDollarsSpent, priceData[0], len(priceData))
-	(*returnPayload).AntiHomeMinerData, (*returnPayload).AntiHomeMinerBitcoin = c.AntiHomeMiner((*returnPayload).FixedCosts, (*returnPayload).ElectricCosts, unixDaysSinceStart, priceData)
+	dates := []time.Time{}
+	for _, p := range priceSeries.Range(startTime, end) {
+		dates = append(dates, p.Time())
+	}
+	flows := OperationCashFlows((*returnPayload).Fleet, dates, (*returnPayload).TotalDollarsSpent, (*returnPayload).FixedCosts, (*returnPayload).ElectricCosts, (*returnPayload).SalePrice, unixDaysSinceStart)
+	(*returnPayload).Strategies, err = RunStrategies(requestPayload.Strategies, flows, priceData)
+	if err != nil {
+		c.Logger.Error("error with RunStrategies: %w", err)
+		return nil, fmt.Errorf("error with RunStrategies: %w", err)
+	}
 
-	rankings := map[float64]string{
-		(*returnPayload).AhBitcoin:            "AmericanHodl",
-		(*returnPayload).DcaBitcoin:           "Daily-DCA",
-		(*returnPayload).AntiHomeMinerBitcoin: "Anti-Miner",
+	if len(ledger) == 0 {
+		(*returnPayload).MinedData = c.MakeMinedBitcoinData(priceData, requestPayload.BitcoinMined)
+	} else {
+		(*returnPayload).MinedData, _, err = CumulativeMined(ledger, dates)
+		if err != nil {
+			c.Logger.Error("error with CumulativeMined: %w", err)
+			return nil, fmt.Errorf("error with CumulativeMined: %w", err)
+		}
+		if requestPayload.Pool != nil {
+			// the ledger is what the pool credited, BitcoinMined what
+			// reached the wallet
+			(*returnPayload).MinedData, err = requestPayload.Pool.NetMinedData((*returnPayload).Pool, (*returnPayload).MinedData, ledgerThrough(ledger, end), dates)
+			if err != nil {
+				c.Logger.Error("error with NetMinedData: %w", err)
+				return nil, fmt.Errorf("error with NetMinedData: %w", err)
+			}
+		}
+	}
+	held, heldData := requestPayload.BitcoinMined, (*returnPayload).MinedData
+	sales, err := requestPayload.CoinSales()
+	if err != nil {
+		c.Logger.Error("error with CoinSales: %w", err)
+		return nil, fmt.Errorf("error with CoinSales: %w", err)
+	}
+	if len(sales) > 0 {
+		(*returnPayload).Sales, (*returnPayload).Strategies, err = ApplySales(sales, flows, priceData, (*returnPayload).MinedData, (*returnPayload).Strategies, (*returnPayload).BitcoinPrice)
+		if err != nil {
+			c.Logger.Error("error with ApplySales: %w", err)
+			return nil, fmt.Errorf("error with ApplySales: %w", err)
+		}
+		held, heldData = (*returnPayload).Sales.HeldBitcoin, (*returnPayload).Sales.HeldData
 	}
 
-	(*returnPayload).Rankings = c.CompareStrategies(requestPayload.BitcoinMined, rankings)
+	(*returnPayload).Rankings = c.CompareStrategies(held, StrategyRankings((*returnPayload).Strategies))
+
+	network := externalData.GetNetworkData()
+	stale := network.Len() > 0 && !network.Covers(end)
+	(*returnPayload).NetworkDataStale = stale
+	// without network data, until the update command creates it, expected
+	// mining is left out like the projection
+	covered := network.Len() > 0 && !stale
+	if covered && (*returnPayload).Fleet != nil && FleetHashrate(requestPayload.Machines) > 0 {
+		(*returnPayload).ExpectedData, (*returnPayload).ExpectedBitcoinMined, err = ExpectedFleetData(network, requestPayload.Machines, requestPayload.UptimePercent, dates)
+		if err != nil {
+			c.Logger.Error("error with ExpectedFleetData: %w", err)
+			return nil, fmt.Errorf("error with ExpectedFleetData: %w", err)
+		}
+	} else if covered && (*returnPayload).Fleet == nil && requestPayload.HashrateTHs > 0 {
+		(*returnPayload).ExpectedData, (*returnPayload).ExpectedBitcoinMined, err = ExpectedMinedData(network, requestPayload.HashrateTHs, requestPayload.UptimePercent, dates)
+		if err != nil {
+			c.Logger.Error("error with ExpectedMinedData: %w", err)
+			return nil, fmt.Errorf("error with ExpectedMinedData: %w", err)
+		}
+	}
+	if (*returnPayload).ExpectedData != nil {
+		if (*returnPayload).ExpectedBitcoinMined > 0 {
+			(*returnPayload).MinedVsExpected = ((*returnPayload).BitcoinMined/(*returnPayload).ExpectedBitcoinMined - 1) * 100
+		}
+		if (*returnPayload).Pool != nil {
+			blocks, err := ExpectedBlocks(network, requestPayload.Machines, requestPayload.HashrateTHs, requestPayload.UptimePercent, dates)
+			if err != nil {
+				c.Logger.Error("error with ExpectedBlocks: %w", err)
+				return nil, fmt.Errorf("error with ExpectedBlocks: %w", err)
+			}
+			requestPayload.Pool.Estimate((*returnPayload).Pool, (*returnPayload).ExpectedBitcoinMined, blocks)
+		}
+	}
+
+	if requestPayload.EndDate != "" {
+		(*returnPayload).Realized = realizedPnL(returnPayload, end)
+	} else if covered {
+		var ledgerMined []float64
+		if len(ledger) > 0 {
+			ledgerMined = (*returnPayload).MinedData
+		}
+		assumptions, err := c.projectionAssumptions(requestPayload, returnPayload, network, dates, ledgerMined, asOf)
+		if err != nil && requestPayload.Simulation != nil {
+			c.Logger.Error("error with projectionAssumptions: %w", err)
+			return nil, fmt.Errorf("error with projectionAssumptions: %w", err)
+		}
+		if err != nil {
+			// the projection is an extra, so the report goes out without it
+			c.Logger.Warnf("leaving out the projection: %s", err)
+		} else {
+			projection := c.Project(assumptions)
+			(*returnPayload).Projection = &projection
+		}
+		if requestPayload.Simulation != nil {
+			first, _ := priceSeries.First()
+			history := priceSeries.Range(first.Time(), asOf)
+			(*returnPayload).Simulation, err = c.Simulate(*requestPayload.Simulation, assumptions, history, (*returnPayload).PriceField)
+			if err != nil {
+				c.Logger.Error("error with Simulate: %w", err)
+				return nil, fmt.Errorf("error with Simulate: %w", err)
+			}
+		}
+	} else if requestPayload.Simulation != nil && stale {
+		last, _ := network.Last()
+		return nil, fmt.Errorf("simulation needs network difficulty data through %s, the data ends %s", asOf.Format("01/02/2006"), last.Time().Format("01/02/2006"))
+	} else if requestPayload.Simulation != nil {
+		return nil, fmt.Errorf("simulation needs network difficulty data, set networkDataPath in the config")
+	}
+
+	if len(ledger) == 0 {
+		return returnPayload, nil
+	}
+	(*returnPayload).RankingsByDay = c.DailyRankings(dates, heldData, (*returnPayload).Strategies)
 	return returnPayload, nil
 }
 
@@ -174,7 +595,15 @@ func (c *Client) GenerateImage(requestPayload RequestPayload, externalData exter
 	if err != nil {
 		return nil, fmt.Errorf("error generating stats: %w", err)
 	}
-	return c.MakePlot(returnPayload.AhData, returnPayload.DcaData, (*returnPayload).AntiHomeMinerData, requestPayload.BitcoinMined, requestPayload.HideBitcoinOnGraph)
+	var fan *FanChart
+	if (*returnPayload).Simulation != nil {
+		fan = &(*returnPayload).Simulation.Fan
+	}
+	minedData := (*returnPayload).MinedData
+	if (*returnPayload).Sales != nil {
+		minedData = (*returnPayload).Sales.HeldData
+	}
+	return c.MakePlot((*returnPayload).Strategies, minedData, (*returnPayload).ExpectedData, fan, requestPayload.HideBitcoinOnGraph)
 }
 
 func (c *Client) AverageCoinsPerDay(days, coins float64) (averageCoinsPerDay float64) {
@@ -191,16 +620,20 @@ func (c *Client) ElectricCosts(kwhPrice, uptimePercentage, uptimeDays, watts flo
 	return electricCosts
 }
 
-func (c *Client) PercentPaidOff(dollarinosEarned, fixedCosts, variableCosts float64) float64 {
-	return dollarinosEarned / (fixedCosts + variableCosts) * 100
+func (c *Client) PercentPaidOff(dollarinosEarned, fixedCosts, variableCosts, salePrice float64) float64 {
+	return dollarinosEarned / (fixedCosts + variableCosts - salePrice) * 100
 }
 
 func (c *Client) DaysSinceStart(startDate string) (*float64, error) {
+	return c.DaysBetween(startDate, time.Now())
+}
+
+func (c *Client) DaysBetween(startDate string, end time.Time) (*float64, error) {
 	t, err := time.Parse("01/02/2006", startDate)
 	if err != nil {
 		return nil, fmt.Errorf("error formating date: %w", err)
 	}
-	durationSinceStart := time.Since(t)
+	durationSinceStart := end.Sub(t)
 	days := durationSinceStart.Hours() / 24
 	return &days, nil
 }
@@ -227,48 +660,20 @@ func (c *Client) DaysUntilBreakeven(daysSinceStart, percentPaidOff float64) floa
 }
 
 func (c *Client) DateFromDaysNow(days float64) (string, error) {
+	return c.DateFromDaysAfter(time.Now(), days)
+}
+
+func (c *Client) DateFromDaysAfter(from time.Time, days float64) (string, error) {
 	hours := days * 24
 	hourDuration, err := time.ParseDuration(fmt.Sprintf("%f", hours) + "h")
 	if err != nil {
 		return "", err
 	}
-	futureTime := time.Now().Add(hourDuration)
+	futureTime := from.Add(hourDuration)
 	futureDate := futureTime.Format("01/02/2006")
 	return futureDate, err
 }
 
-func (c *Client) AmericanHodlSlamBuy(dollarsAvailable, openPrice float64, numberDays int) ([]float64, float64) {
-	cumulativeTotal := make([]float64, 0)
-	bitcoinAcquired := dollarsAvailable / openPrice
-	for i := 0; i < numberDays; i++ {
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return cumulativeTotal, bitcoinAcquired
-}
-
-func (c *Client) DailyDCABuy(dollarsAvialble, daysSinceStart float64, priceData []float64) ([]float64, float64) {
-	dollarsToSpendPerDay := dollarsAvialble / daysSinceStart
-	bitcoinAcquired := 0.0
-	cumulativeTotal := make([]float64, 0)
-	for _, val := range priceData {
-		bitcoinAcquired += dollarsToSpendPerDay / val
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return cumulativeTotal, bitcoinAcquired
-}
-
-func (c *Client) AntiHomeMiner(fixedCosts, electricCosts, daysSinceStart float64, priceData []float64) ([]float64, float64) {
-	bitcoinAcquired := 0.0
-	cumulativeTotal := make([]float64, 0)
-	dollarsToSpendPerDay := electricCosts / daysSinceStart
-
-	for _, val := range priceData {
-		bitcoinAcquired += dollarsToSpendPerDay / val
-		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
-	}
-	return cumulativeTotal, bitcoinAcquired
-}
-
 func (c *Client) CompareData() error {
 	krakenContent, err := os.ReadFile(c.PriceDataKrakenPath)
 	if err != nil {
@@ -297,56 +702,42 @@ func (c *Client) CompareData() error {
 			}
 		}
 		if !foundTimestamp {
-			c.Logger.Info("timestamp: %s  openPrice: %v\n", timestamp, price)
+			c.Logger.Infof("timestamp: %s  openPrice: %v", timestamp, price)
 		}
 
 	}
 	return nil
 }
 
-func (c *Client) CompareStrategies(bitcoinMined float64, m map[float64]string) map[string]float64 {
-
-	results := make(map[float64]float64, len(m))
-	keys := make([]float64, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	sort.Float64s(keys)
-
-	// reverse the order so its in decreasing order
-	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
-		keys[i], keys[j] = keys[j], keys[i]
-	}
-
-	for _, k := range keys {
-		percentage := k / bitcoinMined
+// CompareStrategies compares each of rankings, as StrategyRankings orders
+// them, with bitcoinMined, keeping their order.
+func (c *Client) CompareStrategies(bitcoinMined float64, rankings []StrategyResult) []StrategyRanking {
+	results := make([]StrategyRanking, 0, len(rankings))
+	for _, ranking := range rankings {
+		percentage := ranking.Bitcoin / bitcoinMined
 		switch {
 		case percentage < 1:
 			percentage = -(1 - percentage)
 		case percentage > 1:
 			percentage = percentage - 1
 		}
-		results[k] = percentage * 100
-	}
-	rankingResults := make(map[string]float64, len(m))
-	for _, k := range keys {
-		keyName := m[k]
-		rankingResults[keyName] = results[k]
+		results = append(results, StrategyRanking{Name: ranking.Name, Percent: percentage * 100})
 	}
-
-	return rankingResults
+	return results
 }
 
-func (c *Client) MakeMinedBitcoinData(ahData []float64, minedBitcoin float64) []float64 {
+func (c *Client) MakeMinedBitcoinData(priceData []float64, minedBitcoin float64) []float64 {
 	minedData := []float64{}
-	for range ahData {
+	for range priceData {
 		minedData = append(minedData, minedBitcoin)
 	}
 	return minedData
 }
 
-func (c *Client) MakePlot(ahData, dcaData, antiMinerData []float64, minedBitcoin float64, hideAxis bool) (*string, error) {
-	minedBitcoinData := c.MakeMinedBitcoinData(ahData, minedBitcoin)
+// MakePlot charts the strategies against what was mined. The Expected line is
+// only drawn when expectedData is given, and a simulation's fan chart
+// continues to the right of the history when fan is.
+func (c *Client) MakePlot(strategies []StrategyResult, minedBitcoinData, expectedData []float64, fan *FanChart, hideAxis bool) (*string, error) {
 	p := plot.New()
 	// p.Y.Tick.Label
 	p.Title.Text = "Bitcoin Acquired Over Time"
@@ -362,19 +753,30 @@ func (c *Client) MakePlot(ahData, dcaData, antiMinerData []float64, minedBitcoin
 			Handler: plot.DefaultTextHandler,
 		}
 	}
-	err := plotutil.AddLinePoints(p,
-		"AmericanHodl", c.plotData(ahData),
-		"Daily DCA", c.plotData(dcaData),
-		"Anti-Miner", c.plotData(antiMinerData),
-		"Mined", c.plotData(minedBitcoinData))
+	lines := []interface{}{}
+	for _, strategy := range strategies {
+		lines = append(lines, strategy.Name, c.plotData(strategy.Data))
+	}
+	lines = append(lines, "Mined", c.plotData(minedBitcoinData))
+	if len(expectedData) > 0 {
+		lines = append(lines, "Expected", c.plotData(expectedData))
+	}
+	err := plotutil.AddLinePoints(p, lines...)
 	if err != nil {
 		return nil, fmt.Errorf("error making plot: %w", err)
 	}
+	if err := AddFanChart(p, len(minedBitcoinData)-1, fan); err != nil {
+		return nil, err
+	}
 
 	// Save the plot to a PNG file.
 	fileName := fmt.Sprintf("%d-points.png", time.Now().UnixNano())
 
-	if err := p.Save(4*vg.Inch, 4*vg.Inch, fileName); err != nil {
+	width := 4 * vg.Inch
+	if fan != nil && len(fan.Bands) > 0 {
+		width = 8 * vg.Inch
+	}
+	if err := p.Save(width, 4*vg.Inch, fileName); err != nil {
 		return nil, fmt.Errorf("error saving plot: %w", err)
 	}
 	return &fileName, nil
@@ -0,0 +1,220 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DepreciationStraightLine     = "straight-line"
+	DepreciationDecliningBalance = "declining-balance"
+	DepreciationIndex            = "index"
+
+	// defaultLifeYears is about how long an ASIC stays worth running.
+	defaultLifeYears = 3.0
+)
+
+// PriceIndexPoint is a used ASIC price on Date, in any unit, e.g. USD per
+// TH/s. Only how it moves matters.
+type PriceIndexPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+// DepreciationSchedule says what hardware is worth as it ages.
+//
+// straight-line loses the same amount every day until, after LifeYears, only
+// SalvagePercent of the cost is left. declining-balance loses RatePercent of
+// what is left every year (default twice 100/LifeYears) and never drops
+// below the salvage value. index moves the cost with a used ASIC price
+// index, given as Index or as IndexCsv with a date,value header: hardware
+// bought when the index was 100 is worth half its cost when it is 50.
+type DepreciationSchedule struct {
+	Method         string            `json:"method"`
+	LifeYears      float64           `json:"lifeYears"`
+	SalvagePercent float64           `json:"salvagePercent"`
+	RatePercent    float64           `json:"ratePercent"`
+	Index          []PriceIndexPoint `json:"index"`
+	IndexCsv       string            `json:"indexCsv"`
+}
+
+// HardwareValuer returns what hardware that cost cost on purchased is worth on
+// at.
+type HardwareValuer func(cost float64, purchased, at time.Time) (float64, error)
+
+// Valuer checks the schedule and returns a HardwareValuer for it.
+func (d DepreciationSchedule) Valuer() (HardwareValuer, error) {
+	life := d.LifeYears
+	if life == 0 {
+		life = defaultLifeYears
+	}
+	if life < 0 {
+		return nil, fmt.Errorf("depreciation lifeYears must be positive")
+	}
+	if d.SalvagePercent < 0 || d.SalvagePercent > 100 {
+		return nil, fmt.Errorf("depreciation salvagePercent must be between 0 and 100")
+	}
+	salvage := d.SalvagePercent / 100
+	years := func(purchased, at time.Time) float64 {
+		return math.Max(at.Sub(purchased).Hours()/24/365, 0)
+	}
+
+	switch strings.ToLower(d.Method) {
+	case DepreciationStraightLine, "":
+		return func(cost float64, purchased, at time.Time) (float64, error) {
+			used := math.Min(years(purchased, at)/life, 1)
+			return cost * (1 - (1-salvage)*used), nil
+		}, nil
+	case DepreciationDecliningBalance:
+		rate := d.RatePercent
+		if rate == 0 {
+			rate = 200 / life
+		}
+		if rate <= 0 || rate >= 100 {
+			return nil, fmt.Errorf("depreciation ratePercent must be between 0 and 100")
+		}
+		return func(cost float64, purchased, at time.Time) (float64, error) {
+			value := cost * math.Pow(1-rate/100, years(purchased, at))
+			return math.Max(value, cost*salvage), nil
+		}, nil
+	case DepreciationIndex:
+		index, err := d.priceIndex()
+		if err != nil {
+			return nil, err
+		}
+		return func(cost float64, purchased, at time.Time) (float64, error) {
+			then, err := index.at(purchased)
+			if err != nil {
+				return 0, err
+			}
+			now, err := index.at(at)
+			if err != nil {
+				return 0, err
+			}
+			return cost * now / then, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown depreciation method %q, expected %s, %s or %s", d.Method, DepreciationStraightLine, DepreciationDecliningBalance, DepreciationIndex)
+}
+
+type priceIndex struct {
+	days   []int64
+	values []float64
+}
+
+func (d DepreciationSchedule) priceIndex() (*priceIndex, error) {
+	points := append([]PriceIndexPoint{}, d.Index...)
+	if strings.TrimSpace(d.IndexCsv) != "" {
+		parsed, err := ParsePriceIndexCSV(strings.NewReader(d.IndexCsv))
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, parsed...)
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("the index depreciation method needs a price index")
+	}
+
+	index := &priceIndex{}
+	sort.SliceStable(points, func(i, j int) bool {
+		a, _ := parseLedgerDate(points[i].Date)
+		b, _ := parseLedgerDate(points[j].Date)
+		return a.Before(b)
+	})
+	for _, point := range points {
+		t, err := parseLedgerDate(point.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price index date %q: %w", point.Date, err)
+		}
+		if point.Value <= 0 {
+			return nil, fmt.Errorf("price index value on %s must be positive", point.Date)
+		}
+		index.days = append(index.days, pricedata.Day(t))
+		index.values = append(index.values, point.Value)
+	}
+	return index, nil
+}
+
+// at returns the index on date, carrying the last value before it forward.
+func (p *priceIndex) at(date time.Time) (float64, error) {
+	day := pricedata.Day(date)
+	i := sort.Search(len(p.days), func(i int) bool { return p.days[i] > day })
+	if i == 0 {
+		return 0, fmt.Errorf("price index starts %s, after %s", dayDate(p.days[0]), date.Format("01/02/2006"))
+	}
+	return p.values[i-1], nil
+}
+
+// ParsePriceIndexCSV reads a used ASIC price index with a date,value header.
+// Dates may be mm/dd/yyyy or yyyy-mm-dd.
+func ParsePriceIndexCSV(r io.Reader) ([]PriceIndexPoint, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading price index: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error reading price index: empty file")
+	}
+
+	dateCol, valueCol := -1, -1
+	for i, name := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "date":
+			dateCol = i
+		case "value", "price":
+			valueCol = i
+		}
+	}
+	if dateCol < 0 || valueCol < 0 {
+		return nil, fmt.Errorf("error reading price index: header must have date and value columns")
+	}
+
+	points := make([]PriceIndexPoint, 0, len(records)-1)
+	for line, record := range records[1:] {
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[valueCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error reading price index line %d: %w", line+2, err)
+		}
+		points = append(points, PriceIndexPoint{Date: strings.TrimSpace(record[dateCol]), Value: value})
+	}
+	return points, nil
+}
+
+// LiquidationValue is what the hardware still held on asOf could be sold for
+// by the schedule. A fleet counts each machine bought by asOf and not yet
+// decommissioned. Without one, fixedCosts is the hardware, bought on start.
+func LiquidationValue(schedule DepreciationSchedule, machines []Machine, fixedCosts float64, start, asOf time.Time) (float64, error) {
+	valuer, err := schedule.Valuer()
+	if err != nil {
+		return 0, err
+	}
+	if len(machines) == 0 {
+		return valuer(fixedCosts, start, asOf)
+	}
+
+	fleet, err := parseFleet(machines)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	day := pricedata.Day(asOf)
+	for _, m := range fleet {
+		if m.purchased > day || m.decommissioned <= day {
+			continue
+		}
+		purchased, _ := parseLedgerDate(m.PurchaseDate)
+		value, err := valuer(m.PurchasePrice, purchased, asOf)
+		if err != nil {
+			return 0, fmt.Errorf("error valuing %s: %w", m.name, err)
+		}
+		total += value
+	}
+	return total, nil
+}
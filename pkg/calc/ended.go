@@ -0,0 +1,29 @@
+package calc
+
+import "time"
+
+// RealizedPnL is how a retired operation ended up. Value is the coins mined
+// at the report's price plus whatever the hardware still held could be sold
+// for; Costs is everything spent less hardware already sold.
+type RealizedPnL struct {
+	EndDate       string  `json:"endDate"`
+	DaysOperated  float64 `json:"daysOperated"`
+	Value         float64 `json:"value"`
+	Costs         float64 `json:"costs"`
+	Profit        float64 `json:"profit"`
+	ReturnPercent float64 `json:"returnPercent"`
+}
+
+func realizedPnL(stats *ReturnPayload, end time.Time) *RealizedPnL {
+	realized := &RealizedPnL{
+		EndDate:      end.Format("01/02/2006"),
+		DaysOperated: stats.DaysSinceStarted,
+		Value:        stats.DollarinosEarned + stats.LiquidationValue,
+		Costs:        stats.TotalDollarsSpent,
+	}
+	realized.Profit = realized.Value - realized.Costs
+	if realized.Costs > 0 {
+		realized.ReturnPercent = realized.Profit / realized.Costs * 100
+	}
+	return realized
+}
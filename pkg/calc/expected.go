@@ -0,0 +1,94 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExpectedMinedCoins returns the BTC hashrateTHs should have earned from
+// block subsidies from the start through the end day, given the network
+// difficulty each day and the miners running uptimePercent of the time.
+func (c *Client) ExpectedMinedCoins(network *pricedata.NetworkSeries, hashrateTHs, uptimePercent float64, start, end time.Time) (float64, error) {
+	_, total, err := ExpectedMinedData(network, hashrateTHs, uptimePercent, []time.Time{start, end})
+	return total, err
+}
+
+// HashrateFromMined returns the effective hashrate, uptime and pool luck
+// included, that would have mined bitcoinMined over dates. Only the days the
+// network data covers are counted: the coins mined on them come from
+// minedData, the running total on each of dates, or are the days' share of
+// bitcoinMined when minedData is nil.
+func HashrateFromMined(network *pricedata.NetworkSeries, dates []time.Time, minedData []float64, bitcoinMined float64) (float64, error) {
+	if len(dates) == 0 {
+		return 0, fmt.Errorf("no days to estimate the hashrate from")
+	}
+	first, ok := network.First()
+	if !ok {
+		return 0, fmt.Errorf("no network difficulty data loaded")
+	}
+	covered := sort.Search(len(dates), func(i int) bool {
+		return pricedata.Day(dates[i]) >= pricedata.Day(first.Time())
+	})
+	if covered == len(dates) {
+		return 0, fmt.Errorf("no network difficulty data before %s, data starts %s", dates[len(dates)-1].Format("01/02/2006"), first.Time().Format("01/02/2006"))
+	}
+	_, perTH, err := ExpectedMinedData(network, 1, 100, dates[covered:])
+	if err != nil || perTH <= 0 {
+		return 0, err
+	}
+
+	mined := bitcoinMined
+	last := len(dates) - 1
+	if covered > 0 {
+		if len(minedData) == len(dates) && minedData[last] > 0 {
+			mined *= 1 - minedData[covered-1]/minedData[last]
+		} else {
+			mined *= float64(pricedata.Day(dates[last])-pricedata.Day(dates[covered])+1) / float64(pricedata.Day(dates[last])-pricedata.Day(dates[0])+1)
+		}
+	}
+	return mined / perTH, nil
+}
+
+// ExpectedMinedData returns the running total ExpectedMinedCoins would give
+// by the end of each of dates, which must be in order, and that total on the
+// last date. Like CumulativeMined, days between two dates count towards the
+// later one.
+func ExpectedMinedData(network *pricedata.NetworkSeries, hashrateTHs, uptimePercent float64, dates []time.Time) ([]float64, float64, error) {
+	if hashrateTHs <= 0 {
+		return nil, 0, fmt.Errorf("hashrate must be positive")
+	}
+	return expectedMinedData(network, func(time.Time) float64 { return hashrateTHs }, uptimePercent, dates)
+}
+
+// expectedMinedData is ExpectedMinedData with the hashrate on each day given
+// by hashrateOn.
+func expectedMinedData(network *pricedata.NetworkSeries, hashrateOn func(day time.Time) float64, uptimePercent float64, dates []time.Time) ([]float64, float64, error) {
+	if len(dates) == 0 {
+		return []float64{}, 0, nil
+	}
+	if network.Len() == 0 {
+		return nil, 0, fmt.Errorf("no network difficulty data loaded")
+	}
+	first, _ := network.First()
+	if pricedata.Day(dates[0]) < pricedata.Day(first.Time()) {
+		return nil, 0, fmt.Errorf("no network difficulty data on %s, data starts %s", dates[0].Format("01/02/2006"), first.Time().Format("01/02/2006"))
+	}
+
+	expected := make([]float64, 0, len(dates))
+	total := 0.0
+	day := dates[0]
+	for _, date := range dates {
+		for ; pricedata.Day(day) <= pricedata.Day(date); day = day.AddDate(0, 0, 1) {
+			point, ok := network.AtOrBefore(day)
+			if !ok {
+				last, _ := network.Last()
+				return nil, 0, fmt.Errorf("no network difficulty data on %s, data ends %s", day.Format("01/02/2006"), last.Time().Format("01/02/2006"))
+			}
+			total += point.ExpectedCoins(hashrateOn(day), uptimePercent)
+		}
+		expected = append(expected, total)
+	}
+	return expected, total, nil
+}
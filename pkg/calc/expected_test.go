@@ -0,0 +1,48 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHashrateFromMined(t *testing.T) {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	dates := []time.Time{}
+	for i := 0; i < 10; i++ {
+		dates = append(dates, start.AddDate(0, 0, i))
+	}
+	// 1 TH/s earns 1e-6 BTC a day at this difficulty and a 6.25 subsidy
+	difficulty := 1e12 * 86400 / math.Pow(2, 32) * 6.25 / 1e-6
+	network := func(fromDay int) *pricedata.NetworkSeries {
+		return pricedata.NewNetworkSeries([]pricedata.NetworkPoint{
+			{Timestamp: dates[fromDay].Unix(), Difficulty: difficulty, Subsidy: 6.25},
+		})
+	}
+	tests := []struct {
+		name      string
+		network   *pricedata.NetworkSeries
+		mined     []float64
+		want      float64
+		wantError bool
+	}{
+		{"covers every day", network(0), nil, 100, false},
+		{"starts late, mined evenly", network(5), nil, 100, false},
+		// half of the 1e-3 BTC came in the last five days
+		{"starts late, ledger", network(5), []float64{0, 0, 0, 0, 0.0005, 0.0006, 0.0007, 0.0008, 0.0009, 0.001}, 100, false},
+		{"starts after the last day", pricedata.NewNetworkSeries([]pricedata.NetworkPoint{{Timestamp: dates[9].AddDate(0, 0, 1).Unix(), Difficulty: difficulty, Subsidy: 6.25}}), nil, 0, true},
+		{"no data", pricedata.NewNetworkSeries(nil), nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashrateFromMined(tt.network, dates, tt.mined, 1e-3)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("HashrateFromMined err = %v, wantError %v", err, tt.wantError)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("HashrateFromMined = %v, want %v TH/s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Machine is one miner in a fleet. It is paid for on PurchaseDate and runs
+// from that day until the day before DecommissionDate, or for good when
+// DecommissionDate is empty. ResalePrice comes back on DecommissionDate.
+type Machine struct {
+	Model            string  `json:"model"`
+	PurchaseDate     string  `json:"purchaseDate"`
+	PurchasePrice    float64 `json:"purchasePrice"`
+	Watts            float64 `json:"watts"`
+	HashrateTHs      float64 `json:"hashrateTHs"`
+	DecommissionDate string  `json:"decommissionDate"`
+	ResalePrice      float64 `json:"resalePrice"`
+}
+
+// FleetDay is what the fleet cost on one day and what was running on it.
+type FleetDay struct {
+	Date        string  `json:"date"`
+	Purchases   float64 `json:"purchases"`
+	Electric    float64 `json:"electric"`
+	Resales     float64 `json:"resales"`
+	Watts       float64 `json:"watts"`
+	HashrateTHs float64 `json:"hashrateTHs"`
+	HeatCredit  float64 `json:"heatCredit,omitempty"`
+}
+
+// Outflow is the money that went out on the day, less any that came back or
+// that its heat saved.
+func (d FleetDay) Outflow() float64 {
+	return d.Purchases + d.Electric - d.Resales - d.HeatCredit
+}
+
+// Fleet is a fleet's cash flows day by day from the start date through the
+// end date. Purchases, Resales and ElectricCosts are the totals over Days.
+// Running, Watts, HashrateTHs and DailyElectricCost are for the machines
+// still running on the last day. HeatCredit is the total of the days' heat
+// credits, which ElectricCosts is before.
+type Fleet struct {
+	Purchases         float64    `json:"purchases"`
+	Resales           float64    `json:"resales"`
+	ElectricCosts     float64    `json:"electricCosts"`
+	Running           int        `json:"running"`
+	Watts             float64    `json:"watts"`
+	HashrateTHs       float64    `json:"hashrateTHs"`
+	DailyElectricCost float64    `json:"dailyElectricCost"`
+	HeatCredit        float64    `json:"heatCredit,omitempty"`
+	Days              []FleetDay `json:"days"`
+}
+
+type fleetMachine struct {
+	Machine
+	name                      string
+	purchased, decommissioned int64
+}
+
+func (m fleetMachine) runningOn(day int64) bool {
+	return day >= m.purchased && day < m.decommissioned
+}
+
+func parseFleet(machines []Machine) ([]fleetMachine, error) {
+	fleet := make([]fleetMachine, 0, len(machines))
+	for i, machine := range machines {
+		name := machine.Model
+		if name == "" {
+			name = fmt.Sprintf("machine %d", i+1)
+		}
+		purchased, err := parseLedgerDate(machine.PurchaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s purchase date %q: %w", name, machine.PurchaseDate, err)
+		}
+		if machine.PurchasePrice < 0 || machine.Watts < 0 || machine.HashrateTHs < 0 || machine.ResalePrice < 0 {
+			return nil, fmt.Errorf("%s has a negative price, watts or hashrate", name)
+		}
+		m := fleetMachine{Machine: machine, name: name, purchased: pricedata.Day(purchased), decommissioned: math.MaxInt64}
+		if machine.DecommissionDate != "" {
+			decommissioned, err := parseLedgerDate(machine.DecommissionDate)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing %s decommission date %q: %w", name, machine.DecommissionDate, err)
+			}
+			m.decommissioned = pricedata.Day(decommissioned)
+			if m.decommissioned < m.purchased {
+				return nil, fmt.Errorf("%s is decommissioned on %s before it was purchased", name, machine.DecommissionDate)
+			}
+		} else if machine.ResalePrice > 0 {
+			return nil, fmt.Errorf("%s has a resale price but no decommission date", name)
+		}
+		fleet = append(fleet, m)
+	}
+	return fleet, nil
+}
+
+// dayDate formats a day number from pricedata.Day as mm/dd/yyyy.
+func dayDate(day int64) string {
+	return time.Unix(day*24*60*60, 0).UTC().Format("01/02/2006")
+}
+
+// FleetStartDate returns the first purchase date in machines as mm/dd/yyyy,
+// the day a fleet's operation started.
+func FleetStartDate(machines []Machine) (string, error) {
+	fleet, err := parseFleet(machines)
+	if err != nil {
+		return "", err
+	}
+	if len(fleet) == 0 {
+		return "", fmt.Errorf("no machines")
+	}
+	first := fleet[0].purchased
+	for _, m := range fleet[1:] {
+		if m.purchased < first {
+			first = m.purchased
+		}
+	}
+	return dayDate(first), nil
+}
+
+// FleetSchedule walks the fleet through every day from start to end. Each
+// machine costs its purchase price the day it is bought, its resale price
+// comes back the day it is decommissioned, and it uses its watts at
+// uptimePercent every whole day in between. Machines bought after end are
+// left out and machines bought before start are an error. When electricCosts
+// is given it is spread over the days by the energy each used instead of
+// pricing that energy at kwhPrice.
+func FleetSchedule(machines []Machine, start, end time.Time, kwhPrice, uptimePercent float64, electricCosts *float64) (*Fleet, error) {
+	parsed, err := parseFleet(machines)
+	if err != nil {
+		return nil, err
+	}
+	first, last := pricedata.Day(start), pricedata.Day(end)
+	for _, m := range parsed {
+		if m.purchased < first {
+			return nil, fmt.Errorf("%s was purchased on %s before the start date %s", m.name, m.PurchaseDate, start.Format("01/02/2006"))
+		}
+	}
+
+	fleet := &Fleet{Days: make([]FleetDay, 0, last-first+1)}
+	kwh := make([]float64, 0, last-first+1)
+	totalKwh := 0.0
+	for day := first; day <= last; day++ {
+		fleetDay := FleetDay{Date: dayDate(day)}
+		for _, m := range parsed {
+			if m.purchased == day {
+				fleetDay.Purchases += m.PurchasePrice
+			}
+			if m.decommissioned == day {
+				fleetDay.Resales += m.ResalePrice
+			}
+			if m.runningOn(day) {
+				fleetDay.Watts += m.Watts
+				fleetDay.HashrateTHs += m.HashrateTHs
+			}
+		}
+		dayKwh := fleetDay.Watts * 24 / 1000 * uptimePercent / 100
+		kwh = append(kwh, dayKwh)
+		totalKwh += dayKwh
+		fleet.Days = append(fleet.Days, fleetDay)
+	}
+
+	pricePerKwh := kwhPrice
+	if electricCosts != nil && *electricCosts != 0 {
+		if totalKwh == 0 {
+			return nil, fmt.Errorf("electric costs were given but no machine was running")
+		}
+		pricePerKwh = *electricCosts / totalKwh
+	}
+	for i := range fleet.Days {
+		fleet.Days[i].Electric = kwh[i] * pricePerKwh
+		fleet.Purchases += fleet.Days[i].Purchases
+		fleet.Resales += fleet.Days[i].Resales
+		fleet.ElectricCosts += fleet.Days[i].Electric
+	}
+	if len(fleet.Days) > 0 {
+		lastDay := fleet.Days[len(fleet.Days)-1]
+		fleet.Watts = lastDay.Watts
+		fleet.HashrateTHs = lastDay.HashrateTHs
+		fleet.DailyElectricCost = kwh[len(kwh)-1] * pricePerKwh
+		for _, m := range parsed {
+			if m.runningOn(last) {
+				fleet.Running++
+			}
+		}
+	}
+	return fleet, nil
+}
+
+// ApplyTariff reprices each day's electricity with tariff, for the watts
+// running that day, and returns the monthly bills. start is the fleet's
+// first day.
+func (f *Fleet) ApplyTariff(tariff *Tariff, start time.Time, uptimePercent float64) ([]MonthlyBill, error) {
+	watts := make([]float64, 0, len(f.Days))
+	for _, day := range f.Days {
+		watts = append(watts, day.Watts)
+	}
+	costs, bills, err := tariff.DailyCosts(start, watts, uptimePercent)
+	if err != nil {
+		return nil, err
+	}
+	f.ElectricCosts = 0
+	for i := range f.Days {
+		f.Days[i].Electric = costs[i]
+		f.ElectricCosts += costs[i]
+	}
+	if len(costs) > 0 {
+		f.DailyElectricCost = costs[len(costs)-1]
+	}
+	return bills, nil
+}
+
+// OutflowsOn returns the fleet's outflow on each of dates, which must be in
+// order. Like CumulativeMined, days between two dates count towards the
+// later one; days after the last date count towards it too, so every cost
+// is on some date.
+func (f *Fleet) OutflowsOn(dates []time.Time) []float64 {
+	outflows := make([]float64, len(dates))
+	if len(dates) == 0 || len(f.Days) == 0 {
+		return outflows
+	}
+	first, _ := parseLedgerDate(f.Days[0].Date)
+	i := 0
+	for n, day := range f.Days {
+		for i < len(dates)-1 && pricedata.Day(dates[i]) < pricedata.Day(first)+int64(n) {
+			i++
+		}
+		outflows[i] += day.Outflow()
+	}
+	return outflows
+}
+
+// ExpectedFleetData is ExpectedMinedData for a fleet, with each day's
+// hashrate being that of the machines running on it.
+func ExpectedFleetData(network *pricedata.NetworkSeries, machines []Machine, uptimePercent float64, dates []time.Time) ([]float64, float64, error) {
+	parsed, err := parseFleet(machines)
+	if err != nil {
+		return nil, 0, err
+	}
+	return expectedMinedData(network, func(date time.Time) float64 {
+		hashrate := 0.0
+		for _, m := range parsed {
+			if m.runningOn(pricedata.Day(date)) {
+				hashrate += m.HashrateTHs
+			}
+		}
+		return hashrate
+	}, uptimePercent, dates)
+}
+
+// FleetHashrate returns the combined hashrate listed for machines.
+func FleetHashrate(machines []Machine) float64 {
+	hashrate := 0.0
+	for _, m := range machines {
+		hashrate += m.HashrateTHs
+	}
+	return hashrate
+}
+
+// ParseMachinesCSV reads a fleet with a header naming the model,
+// purchaseDate, purchasePrice, watts, hashrateTHs, decommissionDate and
+// resalePrice columns. Only purchaseDate is required. Dates may be
+// mm/dd/yyyy or yyyy-mm-dd.
+func ParseMachinesCSV(r io.Reader) ([]Machine, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading machines: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error reading machines: empty file")
+	}
+
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["purchasedate"]; !ok {
+		return nil, fmt.Errorf("error reading machines: header must have a purchaseDate column")
+	}
+	if i, ok := cols["hashrate"]; ok {
+		cols["hashrateths"] = i
+	}
+
+	machines := make([]Machine, 0, len(records)-1)
+	for line, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			if field(name) == "" {
+				return 0, nil
+			}
+			return strconv.ParseFloat(field(name), 64)
+		}
+		machine := Machine{Model: field("model"), PurchaseDate: field("purchasedate"), DecommissionDate: field("decommissiondate")}
+		for name, value := range map[string]*float64{
+			"purchaseprice": &machine.PurchasePrice,
+			"watts":         &machine.Watts,
+			"hashrateths":   &machine.HashrateTHs,
+			"resaleprice":   &machine.ResalePrice,
+		} {
+			if *value, err = number(name); err != nil {
+				return nil, fmt.Errorf("error reading machines line %d: %w", line+2, err)
+			}
+		}
+		machines = append(machines, machine)
+	}
+	return machines, nil
+}
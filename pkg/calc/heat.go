@@ -0,0 +1,205 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultFullHeatDegreeDays is the heating degree days (°F) in a day cold
+	// enough for a home to use all of a miner's heat, about 45°F outside.
+	defaultFullHeatDegreeDays = 20.0
+)
+
+// fuelKwh is the energy in one unit of each heating fuel, in kWh.
+var fuelKwh = map[string]float64{
+	"kwh":            1,
+	"therm":          29.3071,
+	"ccf":            30.36,
+	"m3":             10.55,
+	"mmbtu":          293.071,
+	"gallon-propane": 26.8,
+	"gallon-oil":     40.6,
+}
+
+// FuelUnits returns the units a heating fuel price can be given in.
+func FuelUnits() []string {
+	units := make([]string, 0, len(fuelKwh))
+	for unit := range fuelKwh {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+// HeatReuse says how much of the miners' heat replaces other heating and
+// what that heating would have cost.
+//
+// The heating season is either Months (1-12) or DegreeDays, twelve monthly
+// heating degree day totals from January on. With Months every day in them
+// uses UsedPercent of the heat. With DegreeDays a day uses UsedPercent of
+// its share of FullHeatDegreeDays (default 20, for °F), so mild days use
+// less. Each kWh of heat used would otherwise have burned 100 /
+// EfficiencyPercent kWh of fuel costing FuelPrice per FuelUnit (default kwh);
+// a heat pump with a COP of 3 is 300 percent efficient.
+type HeatReuse struct {
+	Months             []int     `json:"months"`
+	DegreeDays         []float64 `json:"degreeDays"`
+	FullHeatDegreeDays float64   `json:"fullHeatDegreeDays"`
+	EfficiencyPercent  float64   `json:"efficiencyPercent"`
+	FuelPrice          float64   `json:"fuelPrice"`
+	FuelUnit           string    `json:"fuelUnit"`
+	UsedPercent        float64   `json:"usedPercent"`
+}
+
+// HeatCredit is what the heat reuse saved. ElectricCosts is the electricity
+// before the credit and NetElectricCosts after it; CostWithoutCredit and
+// CostWithCredit are the same for everything spent.
+type HeatCredit struct {
+	HeatingDays       int     `json:"heatingDays"`
+	HeatKwh           float64 `json:"heatKwh"`
+	Credit            float64 `json:"credit"`
+	SavedPercent      float64 `json:"savedPercent"`
+	ElectricCosts     float64 `json:"electricCosts"`
+	NetElectricCosts  float64 `json:"netElectricCosts"`
+	CostWithoutCredit float64 `json:"costWithoutCredit"`
+	CostWithCredit    float64 `json:"costWithCredit"`
+}
+
+func (h HeatReuse) validate() error {
+	if (len(h.Months) == 0) == (len(h.DegreeDays) == 0) {
+		return fmt.Errorf("heat reuse needs either months or degreeDays for the heating season")
+	}
+	for _, month := range h.Months {
+		if month < 1 || month > 12 {
+			return fmt.Errorf("heat reuse month %d, expected 1 to 12", month)
+		}
+	}
+	if len(h.DegreeDays) > 0 && len(h.DegreeDays) != 12 {
+		return fmt.Errorf("heat reuse degreeDays needs 12 monthly totals, got %d", len(h.DegreeDays))
+	}
+	for _, degreeDays := range h.DegreeDays {
+		if degreeDays < 0 {
+			return fmt.Errorf("heat reuse degreeDays can't be negative")
+		}
+	}
+	if h.FullHeatDegreeDays < 0 || h.EfficiencyPercent < 0 || h.FuelPrice < 0 {
+		return fmt.Errorf("heat reuse fullHeatDegreeDays, efficiencyPercent and fuelPrice can't be negative")
+	}
+	if h.UsedPercent < 0 || h.UsedPercent > 100 {
+		return fmt.Errorf("heat reuse usedPercent must be between 0 and 100")
+	}
+	if _, ok := fuelKwh[h.fuelUnit()]; !ok {
+		return fmt.Errorf("unknown fuel unit %q, expected one of %s", h.FuelUnit, strings.Join(FuelUnits(), ", "))
+	}
+	return nil
+}
+
+func (h HeatReuse) fuelUnit() string {
+	if h.FuelUnit == "" {
+		return "kwh"
+	}
+	return strings.ToLower(h.FuelUnit)
+}
+
+// usedShare is the share of the day's heat that replaced other heating.
+func (h HeatReuse) usedShare(date time.Time) float64 {
+	used := h.UsedPercent
+	if used == 0 {
+		used = 100
+	}
+	if len(h.Months) > 0 {
+		for _, month := range h.Months {
+			if time.Month(month) == date.Month() {
+				return used / 100
+			}
+		}
+		return 0
+	}
+	full := h.FullHeatDegreeDays
+	if full == 0 {
+		full = defaultFullHeatDegreeDays
+	}
+	daysInMonth := float64(time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+	perDay := h.DegreeDays[date.Month()-1] / daysInMonth
+	return used / 100 * math.Min(perDay/full, 1)
+}
+
+// Credits works out what the heat used on the i-th day from start, from a
+// load of watts[i] at uptimePercent, saved on other heating. A day's credit
+// never goes above electric[i], what its electricity cost: heat can make
+// mining's power free but not pay for the rest.
+func (h HeatReuse) Credits(start time.Time, watts, electric []float64, uptimePercent float64) ([]float64, *HeatCredit, error) {
+	if err := h.validate(); err != nil {
+		return nil, nil, err
+	}
+	efficiency := h.EfficiencyPercent
+	if efficiency == 0 {
+		efficiency = 100
+	}
+	pricePerKwh := h.FuelPrice / fuelKwh[h.fuelUnit()] / (efficiency / 100)
+
+	credits := make([]float64, len(watts))
+	summary := &HeatCredit{}
+	for i, w := range watts {
+		share := h.usedShare(start.AddDate(0, 0, i))
+		heatKwh := w * 24 / 1000 * uptimePercent / 100 * share
+		credits[i] = math.Min(heatKwh*pricePerKwh, electric[i])
+		if share > 0 && w > 0 {
+			summary.HeatingDays++
+		}
+		summary.HeatKwh += heatKwh
+		summary.Credit += credits[i]
+		summary.ElectricCosts += electric[i]
+	}
+	summary.NetElectricCosts = summary.ElectricCosts - summary.Credit
+	if summary.ElectricCosts > 0 {
+		summary.SavedPercent = summary.Credit / summary.ElectricCosts * 100
+	}
+	return credits, summary, nil
+}
+
+// ApplyHeatReuse credits heat reuse day by day from start through end. A
+// fleet is credited for the watts running each day and keeps each day's
+// credit. Without one a steady load of watts costs electricCosts in all,
+// spread evenly over the days, or as tariff prices each day when given.
+func ApplyHeatReuse(heat HeatReuse, fleet *Fleet, tariff *Tariff, start, end time.Time, watts, uptimePercent, electricCosts float64) (*HeatCredit, error) {
+	var loads, electric []float64
+	if fleet != nil {
+		for _, day := range fleet.Days {
+			loads = append(loads, day.Watts)
+			electric = append(electric, day.Electric)
+		}
+	} else {
+		days := pricedata.Day(end) - pricedata.Day(start) + 1
+		if days <= 0 {
+			return nil, fmt.Errorf("end %s is before start %s", end.Format("01/02/2006"), start.Format("01/02/2006"))
+		}
+		for i := int64(0); i < days; i++ {
+			loads = append(loads, watts)
+			electric = append(electric, electricCosts/float64(days))
+		}
+		if tariff != nil {
+			var err error
+			if electric, _, err = tariff.DailyCosts(start, loads, uptimePercent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	credits, summary, err := heat.Credits(start, loads, electric, uptimePercent)
+	if err != nil {
+		return nil, err
+	}
+	if fleet != nil {
+		fleet.HeatCredit = summary.Credit
+		for i := range fleet.Days {
+			fleet.Days[i].HeatCredit = credits[i]
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,159 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinedEntry is BTC credited on one day, either a day's earnings or a single
+// payout. Several entries on the same day add up.
+type MinedEntry struct {
+	Date    string  `json:"date"`
+	Bitcoin float64 `json:"bitcoin"`
+}
+
+// DailyRanking is how each strategy compared to what had been mined by Date.
+type DailyRanking struct {
+	Date     string            `json:"date"`
+	Mined    float64           `json:"mined"`
+	Rankings []StrategyRanking `json:"rankings"`
+}
+
+// ParseMinedLedgerCSV reads a ledger with a date,bitcoin header. Dates may be
+// mm/dd/yyyy or yyyy-mm-dd.
+func ParseMinedLedgerCSV(r io.Reader) ([]MinedEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading mined ledger: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error reading mined ledger: empty file")
+	}
+
+	dateCol, btcCol := -1, -1
+	for i, name := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "date":
+			dateCol = i
+		case "bitcoin", "btc":
+			btcCol = i
+		}
+	}
+	if dateCol < 0 || btcCol < 0 {
+		return nil, fmt.Errorf("error reading mined ledger: header must have date and bitcoin columns")
+	}
+
+	ledger := make([]MinedEntry, 0, len(records)-1)
+	for line, record := range records[1:] {
+		bitcoin, err := strconv.ParseFloat(strings.TrimSpace(record[btcCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error reading mined ledger line %d: %w", line+2, err)
+		}
+		ledger = append(ledger, MinedEntry{Date: strings.TrimSpace(record[dateCol]), Bitcoin: bitcoin})
+	}
+	return ledger, nil
+}
+
+// Ledger returns the request's mined ledger, combining minedLedger with the
+// rows of minedLedgerCsv.
+func (r RequestPayload) Ledger() ([]MinedEntry, error) {
+	ledger := append([]MinedEntry{}, r.MinedLedger...)
+	if strings.TrimSpace(r.MinedLedgerCsv) != "" {
+		entries, err := ParseMinedLedgerCSV(strings.NewReader(r.MinedLedgerCsv))
+		if err != nil {
+			return nil, err
+		}
+		ledger = append(ledger, entries...)
+	}
+	return ledger, nil
+}
+
+func parseLedgerDate(date string) (time.Time, error) {
+	if t, err := time.Parse("01/02/2006", date); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", date)
+}
+
+// CumulativeMined returns the total mined by the end of each of dates, which
+// must be in order, and that total on the last date. Entries after the last
+// date are left out, so an as-of report only counts what had been mined by
+// then; entries before the first date are an error.
+func CumulativeMined(ledger []MinedEntry, dates []time.Time) ([]float64, float64, error) {
+	if len(dates) == 0 {
+		return []float64{}, 0, nil
+	}
+	byDay := map[int64]float64{}
+	first, last := pricedata.Day(dates[0]), pricedata.Day(dates[len(dates)-1])
+	for _, entry := range ledger {
+		t, err := parseLedgerDate(entry.Date)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error parsing mined ledger date %q: %w", entry.Date, err)
+		}
+		if entry.Bitcoin < 0 {
+			return nil, 0, fmt.Errorf("mined ledger entry on %s is negative", entry.Date)
+		}
+		day := pricedata.Day(t)
+		if day < first {
+			return nil, 0, fmt.Errorf("mined ledger entry on %s is before the start date %s", entry.Date, dates[0].Format("01/02/2006"))
+		}
+		if day > last {
+			continue
+		}
+		byDay[day] += entry.Bitcoin
+	}
+
+	// entries on days the price data skips are counted on the next day it has
+	cumulative := make([]float64, 0, len(dates))
+	total := 0.0
+	next := first
+	for _, date := range dates {
+		for day := next; day <= pricedata.Day(date); day++ {
+			total += byDay[day]
+		}
+		next = pricedata.Day(date) + 1
+		cumulative = append(cumulative, total)
+	}
+	return cumulative, total, nil
+}
+
+// ledgerThrough returns the entries on or before end, the ones an as-of
+// report counts. Dates are assumed to have been checked by CumulativeMined.
+func ledgerThrough(ledger []MinedEntry, end time.Time) []MinedEntry {
+	through := make([]MinedEntry, 0, len(ledger))
+	for _, entry := range ledger {
+		if t, err := parseLedgerDate(entry.Date); err == nil && pricedata.Day(t) <= pricedata.Day(end) {
+			through = append(through, entry)
+		}
+	}
+	return through
+}
+
+// DailyRankings compares each strategy's running total with what had been
+// mined on the same day. Days before anything was mined are skipped since
+// there is nothing to compare against.
+func (c *Client) DailyRankings(dates []time.Time, minedData []float64, strategies []StrategyResult) []DailyRanking {
+	rankings := []DailyRanking{}
+	for i, date := range dates {
+		if i >= len(minedData) || minedData[i] <= 0 {
+			continue
+		}
+		day := make([]StrategyResult, 0, len(strategies))
+		for _, strategy := range strategies {
+			if i < len(strategy.Data) {
+				day = append(day, StrategyResult{Name: strategy.Name, Bitcoin: strategy.Data[i]})
+			}
+		}
+		rankings = append(rankings, DailyRanking{
+			Date:     date.Format("01/02/2006"),
+			Mined:    minedData[i],
+			Rankings: c.CompareStrategies(minedData[i], StrategyRankings(day)),
+		})
+	}
+	return rankings
+}
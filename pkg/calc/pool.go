@@ -0,0 +1,245 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	SchemeFPPS    = "fpps"
+	SchemePPSPlus = "pps+"
+	SchemePPS     = "pps"
+	SchemePPLNS   = "pplns"
+	SchemeSolo    = "solo"
+)
+
+// PayoutSchemes returns the pool payout schemes a PoolPayout can use.
+func PayoutSchemes() []string {
+	return []string{SchemeFPPS, SchemePPSPlus, SchemePPS, SchemePPLNS, SchemeSolo}
+}
+
+// PoolOption is a pool and payout scheme to compare with the one used.
+type PoolOption struct {
+	Name          string  `json:"name"`
+	Scheme        string  `json:"scheme"`
+	FeePercent    float64 `json:"feePercent"`
+	WithdrawalFee float64 `json:"withdrawalFee"`
+}
+
+// PoolPayout is how mined coins reached the wallet. The bitcoin mined is
+// what the pool credited, after its FeePercent; every one of Payouts (by
+// default the ledger's entries) then cost WithdrawalFee BTC on chain.
+//
+// FPPS pays for the block subsidy and the transaction fees blocks carry,
+// TxFeePercent of the subsidy; PPS+ pays the subsidy the same way but the
+// fees as they are found; PPS pays the subsidy alone. PPLNS pays out the
+// pool's real blocks and solo the miner's own, so they earn the same as FPPS
+// on average but with the pool's or the miner's luck. Compare lists other
+// pools to estimate when the hashrate is known; without it every scheme is
+// compared at the same fees and solo at none.
+type PoolPayout struct {
+	PoolOption
+	Payouts      int          `json:"payouts"`
+	TxFeePercent float64      `json:"txFeePercent"`
+	Compare      []PoolOption `json:"compare"`
+}
+
+// PoolEstimate is what a pool option should have paid the hashrate, net of
+// its fees, and how that compares with the pool used. BlockChancePercent is
+// the chance of solo mining finding at least one block.
+type PoolEstimate struct {
+	PoolOption
+	ExpectedBitcoin    float64 `json:"expectedBitcoin"`
+	Change             float64 `json:"change"`
+	ExpectedBlocks     float64 `json:"expectedBlocks,omitempty"`
+	BlockChancePercent float64 `json:"blockChancePercent,omitempty"`
+}
+
+// PoolReport splits the mined coins into what the hashrate earned, GrossBitcoin,
+// the pool's and withdrawal fees, and what reached the wallet, NetBitcoin.
+// With a known hashrate ExpectedBitcoin is what the pool used should have
+// paid, LuckPercent how far the gross was above it and Estimates what the
+// other options should have paid.
+type PoolReport struct {
+	Scheme          string         `json:"scheme"`
+	FeePercent      float64        `json:"feePercent"`
+	Payouts         int            `json:"payouts"`
+	GrossBitcoin    float64        `json:"grossBitcoin"`
+	PoolFees        float64        `json:"poolFees"`
+	WithdrawalFees  float64        `json:"withdrawalFees"`
+	NetBitcoin      float64        `json:"netBitcoin"`
+	ExpectedBitcoin float64        `json:"expectedBitcoin,omitempty"`
+	LuckPercent     float64        `json:"luckPercent,omitempty"`
+	Estimates       []PoolEstimate `json:"estimates,omitempty"`
+}
+
+func (o PoolOption) scheme() string {
+	if o.Scheme == "" {
+		return SchemeFPPS
+	}
+	return strings.ToLower(o.Scheme)
+}
+
+func (o PoolOption) validate() error {
+	known := false
+	for _, scheme := range PayoutSchemes() {
+		known = known || o.scheme() == scheme
+	}
+	if !known {
+		return fmt.Errorf("unknown payout scheme %q, expected one of %s", o.Scheme, strings.Join(PayoutSchemes(), ", "))
+	}
+	if o.FeePercent < 0 || o.FeePercent >= 100 {
+		return fmt.Errorf("pool feePercent must be at least 0 and under 100")
+	}
+	if o.WithdrawalFee < 0 {
+		return fmt.Errorf("pool withdrawalFee can't be negative")
+	}
+	return nil
+}
+
+// Share is what the option pays of the expected block subsidy.
+func (o PoolOption) Share(txFeePercent float64) float64 {
+	share := 1 - o.FeePercent/100
+	if o.scheme() != SchemePPS {
+		share *= 1 + txFeePercent/100
+	}
+	return share
+}
+
+// Report splits mined, what the pool credited, into gross, fees and net.
+func (p PoolPayout) Report(mined float64, ledger []MinedEntry) (*PoolReport, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.Payouts < 0 || p.TxFeePercent < 0 {
+		return nil, fmt.Errorf("pool payouts and txFeePercent can't be negative")
+	}
+	for _, option := range p.Compare {
+		if err := option.validate(); err != nil {
+			return nil, fmt.Errorf("error with pool option %s: %w", option.Name, err)
+		}
+	}
+	report := &PoolReport{Scheme: p.scheme(), FeePercent: p.FeePercent, Payouts: p.Payouts}
+	if report.Payouts == 0 {
+		for _, entry := range ledger {
+			if entry.Bitcoin > 0 {
+				report.Payouts++
+			}
+		}
+	}
+	report.GrossBitcoin = mined / (1 - p.FeePercent/100)
+	report.PoolFees = report.GrossBitcoin - mined
+	report.WithdrawalFees = float64(report.Payouts) * p.WithdrawalFee
+	report.NetBitcoin = mined - report.WithdrawalFees
+	if report.NetBitcoin < 0 {
+		return nil, fmt.Errorf("withdrawal fees of %.8f BTC are more than the %.8f BTC mined", report.WithdrawalFees, mined)
+	}
+	return report, nil
+}
+
+// NetMinedData takes the withdrawal fees off mined, the running total the
+// pool credited by each of dates, so it ends at report's NetBitcoin. When
+// the payouts are the ledger's entries each fee comes off on its payout's
+// day; a given number of payouts can't be placed, so the whole curve is
+// scaled down instead.
+func (p PoolPayout) NetMinedData(report *PoolReport, mined []float64, ledger []MinedEntry, dates []time.Time) ([]float64, error) {
+	net := make([]float64, len(mined))
+	if p.Payouts == 0 && len(ledger) > 0 {
+		fees := make([]MinedEntry, 0, len(ledger))
+		for _, entry := range ledger {
+			if entry.Bitcoin > 0 {
+				fees = append(fees, MinedEntry{Date: entry.Date, Bitcoin: p.WithdrawalFee})
+			}
+		}
+		paid, _, err := CumulativeMined(fees, dates)
+		if err != nil {
+			return nil, err
+		}
+		for i := range mined {
+			net[i] = mined[i] - paid[i]
+		}
+		return net, nil
+	}
+	credited := report.NetBitcoin + report.WithdrawalFees
+	for i := range mined {
+		net[i] = mined[i]
+		if credited > 0 {
+			net[i] *= report.NetBitcoin / credited
+		}
+	}
+	return net, nil
+}
+
+// Estimate fills in what each pool option should have paid a hashrate that
+// was expected to earn expectedSubsidy BTC of block subsidies by finding
+// blocks blocks. Options pay the same number of withdrawals as the pool
+// used, except solo, which is paid straight to the miner.
+func (p PoolPayout) Estimate(report *PoolReport, expectedSubsidy, blocks float64) {
+	withdrawals := func(o PoolOption) float64 {
+		if o.scheme() == SchemeSolo {
+			return 0
+		}
+		return float64(report.Payouts) * o.WithdrawalFee
+	}
+	report.ExpectedBitcoin = expectedSubsidy*p.Share(p.TxFeePercent) - withdrawals(p.PoolOption)
+	if expectedGross := expectedSubsidy * p.Share(p.TxFeePercent) / (1 - p.FeePercent/100); expectedGross > 0 {
+		report.LuckPercent = (report.GrossBitcoin/expectedGross - 1) * 100
+	}
+
+	options := p.Compare
+	if len(options) == 0 {
+		for _, scheme := range PayoutSchemes() {
+			option := PoolOption{Name: scheme, Scheme: scheme, FeePercent: p.FeePercent, WithdrawalFee: p.WithdrawalFee}
+			if scheme == SchemeSolo {
+				option.FeePercent, option.WithdrawalFee = 0, 0
+			}
+			options = append(options, option)
+		}
+	}
+	report.Estimates = make([]PoolEstimate, 0, len(options))
+	for _, option := range options {
+		estimate := PoolEstimate{PoolOption: option}
+		estimate.Scheme = option.scheme()
+		estimate.ExpectedBitcoin = expectedSubsidy*option.Share(p.TxFeePercent) - withdrawals(option)
+		estimate.Change = estimate.ExpectedBitcoin - report.ExpectedBitcoin
+		if estimate.Scheme == SchemeSolo {
+			estimate.ExpectedBlocks = blocks
+			estimate.BlockChancePercent = (1 - math.Exp(-blocks)) * 100
+		}
+		report.Estimates = append(report.Estimates, estimate)
+	}
+}
+
+// ExpectedBlocks is how many blocks the hashrate should have found from the
+// first through the last of dates: a fleet's machines running each day, or
+// hashrateTHs without machines.
+func ExpectedBlocks(network *pricedata.NetworkSeries, machines []Machine, hashrateTHs, uptimePercent float64, dates []time.Time) (float64, error) {
+	if len(dates) == 0 {
+		return 0, nil
+	}
+	parsed, err := parseFleet(machines)
+	if err != nil {
+		return 0, err
+	}
+	blocks := 0.0
+	for day := dates[0]; pricedata.Day(day) <= pricedata.Day(dates[len(dates)-1]); day = day.AddDate(0, 0, 1) {
+		point, ok := network.AtOrBefore(day)
+		if !ok || point.Subsidy <= 0 {
+			continue
+		}
+		hashrate := hashrateTHs
+		if len(parsed) > 0 {
+			hashrate = 0
+			for _, m := range parsed {
+				if m.runningOn(pricedata.Day(day)) {
+					hashrate += m.HashrateTHs
+				}
+			}
+		}
+		blocks += point.ExpectedCoins(hashrate, uptimePercent) / point.Subsidy
+	}
+	return blocks, nil
+}
@@ -0,0 +1,48 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNetMinedData(t *testing.T) {
+	dates := testDates(0, 1, 2, 3)
+	ledger := []MinedEntry{
+		{Date: "07/01/2022", Bitcoin: 0.01},
+		{Date: "07/03/2022", Bitcoin: 0.01},
+		{Date: "07/04/2022", Bitcoin: 0.01},
+	}
+	mined, total, err := CumulativeMined(ledger, dates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		pool PoolPayout
+		want []float64
+	}{
+		{"fee on each payout day", PoolPayout{PoolOption: PoolOption{WithdrawalFee: 0.001}}, []float64{0.009, 0.009, 0.018, 0.027}},
+		{"given payouts scale the curve", PoolPayout{PoolOption: PoolOption{WithdrawalFee: 0.001}, Payouts: 6}, []float64{0.008, 0.008, 0.016, 0.024}},
+		{"no withdrawal fee", PoolPayout{PoolOption: PoolOption{FeePercent: 2}}, []float64{0.01, 0.01, 0.02, 0.03}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := tt.pool.Report(total, ledger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.pool.NetMinedData(report, mined, ledger, dates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-12 {
+					t.Fatalf("NetMinedData = %v, want %v", got, tt.want)
+				}
+			}
+			if last := got[len(got)-1]; math.Abs(last-report.NetBitcoin) > 1e-12 {
+				t.Fatalf("curve ends at %v, want NetBitcoin %v", last, report.NetBitcoin)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	blocksPerDay    = 144
+	halvingInterval = pricedata.HalvingInterval
+	// retargetDays is roughly how long 2016 blocks take, so projected
+	// difficulty moves in steps like the real one does.
+	retargetDays = 14
+	// defaultProjectionDays is five years, about as long as a miner stays
+	// worth running.
+	defaultProjectionDays = 1825
+)
+
+// Without a height from the network data, block heights are estimated from
+// the fourth halving, block 840000, at 144 blocks a day. Blocks come a little
+// faster while hashrate grows, so estimates run later the further they are
+// from it.
+var (
+	halvingAnchorHeight int64 = 840000
+	halvingAnchorTime         = time.Date(2024, 4, 20, 0, 9, 27, 0, time.UTC)
+)
+
+// EstimatedHeight returns the block height expected at date.
+func EstimatedHeight(date time.Time) int64 {
+	return heightFrom(halvingAnchorHeight, halvingAnchorTime, date)
+}
+
+// EstimatedDateOfHeight returns when height is expected to be mined.
+func EstimatedDateOfHeight(height int64) time.Time {
+	return dateOfHeightFrom(halvingAnchorHeight, halvingAnchorTime, height)
+}
+
+// heightFrom returns the height expected at date given the block at
+// anchorHeight was mined at anchorTime.
+func heightFrom(anchorHeight int64, anchorTime, date time.Time) int64 {
+	days := date.Sub(anchorTime).Hours() / 24
+	return anchorHeight + int64(math.Floor(days*blocksPerDay))
+}
+
+func dateOfHeightFrom(anchorHeight int64, anchorTime time.Time, height int64) time.Time {
+	days := float64(height-anchorHeight) / blocksPerDay
+	return anchorTime.Add(time.Duration(days * 24 * float64(time.Hour)))
+}
+
+// Subsidy returns the block subsidy in BTC at height, halving every 210000
+// blocks.
+func Subsidy(height int64) float64 {
+	return pricedata.BlockSubsidy(height)
+}
+
+// NextHalvingHeight returns the height of the first halving after height.
+func NextHalvingHeight(height int64) int64 {
+	return (height/halvingInterval + 1) * halvingInterval
+}
+
+// ProjectionAssumptions are what a projection walks forward from.
+type ProjectionAssumptions struct {
+	// Start is the last day already mined; the projection begins the day
+	// after.
+	Start time.Time
+	// HashrateTHs is the effective hashrate, uptime and luck included.
+	HashrateTHs float64
+	// Difficulty is the network difficulty on Start and Height the block
+	// height then, zero to estimate it from the last halving.
+	Difficulty float64
+	Height     int64
+	// DifficultyGrowthPercent and PriceGrowthPercent are yearly rates.
+	// Difficulty grows in two week steps, price grows daily.
+	DifficultyGrowthPercent float64
+	Price                   float64
+	PriceGrowthPercent      float64
+	// BitcoinMined and Costs are the totals by Start, DailyCost what each
+	// day after it costs to run.
+	BitcoinMined float64
+	Costs        float64
+	DailyCost    float64
+	// Days is how far ahead to look for breakeven.
+	Days int
+}
+
+// heightOn returns the block height expected at date, counted on from
+// a.Height at 144 blocks a day when it is known.
+func (a ProjectionAssumptions) heightOn(date time.Time) int64 {
+	if a.Height <= 0 {
+		return EstimatedHeight(date)
+	}
+	return heightFrom(a.Height, a.Start, date)
+}
+
+// dateOfHeight returns when height is expected to be mined.
+func (a ProjectionAssumptions) dateOfHeight(height int64) time.Time {
+	if a.Height <= 0 {
+		return EstimatedDateOfHeight(height)
+	}
+	return dateOfHeightFrom(a.Height, a.Start, height)
+}
+
+// ProjectedDay is one day of a projection. BitcoinMined, Value and Costs are
+// running totals including everything before the projection started.
+type ProjectedDay struct {
+	Date         string  `json:"date"`
+	Height       int64   `json:"height"`
+	Subsidy      float64 `json:"subsidy"`
+	Difficulty   float64 `json:"difficulty"`
+	Price        float64 `json:"price"`
+	BitcoinMined float64 `json:"bitcoinMined"`
+	Value        float64 `json:"value"`
+	Costs        float64 `json:"costs"`
+}
+
+// Projection is where mining is headed. BreakevenDate is empty and
+// DaysUntilBreakeven -1 when the value of the coins doesn't catch up with
+// the costs within the projected days. Days stops at breakeven.
+type Projection struct {
+	BreakevenDate      string         `json:"breakevenDate"`
+	DaysUntilBreakeven float64        `json:"daysUntilBreakeven"`
+	NextHalvingHeight  int64          `json:"nextHalvingHeight"`
+	NextHalvingDate    string         `json:"nextHalvingDate"`
+	Days               []ProjectedDay `json:"days"`
+}
+
+// Project walks forward from a.Start one day at a time. Each day the
+// hashrate earns its share of the subsidy at that day's expected height and
+// projected difficulty, the coins mined so far are valued at that day's
+// projected price, and breakeven is the first day that value covers every
+// cost paid so far.
+func (c *Client) Project(a ProjectionAssumptions) Projection {
+	next := NextHalvingHeight(a.heightOn(a.Start))
+	projection := Projection{
+		BreakevenDate:      "",
+		DaysUntilBreakeven: -1,
+		NextHalvingHeight:  next,
+		NextHalvingDate:    a.dateOfHeight(next).Format("01/02/2006"),
+		Days:               []ProjectedDay{},
+	}
+	if a.BitcoinMined*a.Price >= a.Costs {
+		projection.BreakevenDate = a.Start.Format("01/02/2006")
+		projection.DaysUntilBreakeven = 0
+		return projection
+	}
+
+	points, mined, costs := projectMining(a)
+	for i, point := range points {
+		day := i + 1
+		price := a.Price * math.Pow(1+a.PriceGrowthPercent/100, float64(day)/365)
+		projection.Days = append(projection.Days, ProjectedDay{
+			Date:         point.Time().Format("01/02/2006"),
+			Height:       a.heightOn(point.Time()),
+			Subsidy:      point.Subsidy,
+			Difficulty:   point.Difficulty,
+			Price:        price,
+			BitcoinMined: mined[i],
+			Value:        mined[i] * price,
+			Costs:        costs[i],
+		})
+		if mined[i]*price >= costs[i] {
+			projection.BreakevenDate = point.Time().Format("01/02/2006")
+			projection.DaysUntilBreakeven = float64(day)
+			break
+		}
+	}
+	return projection
+}
+
+// projectMining returns the projected network for each of the a.Days days
+// after a.Start, with the running totals of bitcoin mined and costs paid by
+// the end of each. Neither depends on the price, so simulations share them.
+func projectMining(a ProjectionAssumptions) ([]pricedata.NetworkPoint, []float64, []float64) {
+	points := make([]pricedata.NetworkPoint, 0, a.Days)
+	mined := make([]float64, 0, a.Days)
+	costs := make([]float64, 0, a.Days)
+	totalMined, totalCosts := a.BitcoinMined, a.Costs
+	for day := 1; day <= a.Days; day++ {
+		date := a.Start.AddDate(0, 0, day)
+		steps := float64(day / retargetDays * retargetDays)
+		point := pricedata.NetworkPoint{
+			Timestamp:  date.Unix(),
+			Difficulty: a.Difficulty * math.Pow(1+a.DifficultyGrowthPercent/100, steps/365),
+			Subsidy:    Subsidy(a.heightOn(date)),
+		}
+		totalMined += point.ExpectedCoins(a.HashrateTHs, 100)
+		totalCosts += a.DailyCost
+		points = append(points, point)
+		mined = append(mined, totalMined)
+		costs = append(costs, totalCosts)
+	}
+	return points, mined, costs
+}
+
+// projectionAssumptions sets up a projection of a report forward from asOf.
+// Without a hashrate the effective one is estimated from what was mined, see
+// HashrateFromMined, with ledgerMined the running total the ledger gives for
+// each of dates, nil without one. A fleet is projected as the machines still
+// running on asOf. The height on asOf is counted on from the last network
+// point's when the data has heights.
+func (c *Client) projectionAssumptions(requestPayload RequestPayload, stats *ReturnPayload, network *pricedata.NetworkSeries, dates []time.Time, ledgerMined []float64, asOf time.Time) (ProjectionAssumptions, error) {
+	listedHashrate, dailyCost := requestPayload.HashrateTHs, stats.DailyElectricCost
+	if stats.Fleet != nil {
+		listedHashrate, dailyCost = stats.Fleet.HashrateTHs, stats.Fleet.DailyElectricCost
+		if stats.HeatReuse != nil {
+			// The last day's heat credit depends on its season, so take off
+			// the share heat saved over the whole operation instead.
+			dailyCost *= 1 - stats.HeatReuse.SavedPercent/100
+		}
+	}
+	hashrate := listedHashrate * requestPayload.UptimePercent / 100
+	if requestPayload.Pool != nil {
+		// a listed hashrate earns what the pool pays of the subsidy
+		hashrate *= requestPayload.Pool.Share(requestPayload.Pool.TxFeePercent)
+	}
+	if listedHashrate <= 0 {
+		var err error
+		if hashrate, err = HashrateFromMined(network, dates, ledgerMined, stats.BitcoinMined); err != nil {
+			return ProjectionAssumptions{}, err
+		}
+	}
+	point, ok := network.AtOrBefore(asOf)
+	if !ok {
+		return ProjectionAssumptions{}, fmt.Errorf("no network difficulty data on %s", asOf.Format("01/02/2006"))
+	}
+	height := int64(0)
+	if point.Height > 0 {
+		height = heightFrom(point.Height, point.Time(), asOf)
+	}
+
+	difficultyGrowth := c.DifficultyGrowthPercent
+	if requestPayload.DifficultyGrowthPercent != nil {
+		difficultyGrowth = *requestPayload.DifficultyGrowthPercent
+	}
+	priceGrowth := c.PriceGrowthPercent
+	if requestPayload.PriceGrowthPercent != nil {
+		priceGrowth = *requestPayload.PriceGrowthPercent
+	}
+	price := stats.BitcoinPrice
+	if requestPayload.ProjectedPrice > 0 {
+		price = requestPayload.ProjectedPrice
+	}
+
+	return ProjectionAssumptions{
+		Start:                   asOf,
+		HashrateTHs:             hashrate,
+		Difficulty:              point.Difficulty,
+		Height:                  height,
+		DifficultyGrowthPercent: difficultyGrowth,
+		Price:                   price,
+		PriceGrowthPercent:      priceGrowth,
+		BitcoinMined:            stats.BitcoinMined,
+		Costs:                   stats.TotalDollarsSpent,
+		DailyCost:               dailyCost,
+		Days:                    c.ProjectionDays,
+	}, nil
+}
@@ -0,0 +1,185 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoinSale is Bitcoin sold or spent on Date for Proceeds in the report's
+// currency. Lots, for specific-ID lot matching, lists the receipt dates of
+// the lots it came out of, used up in order.
+type CoinSale struct {
+	Date     string   `json:"date"`
+	Bitcoin  float64  `json:"bitcoin"`
+	Proceeds float64  `json:"proceeds"`
+	Lots     []string `json:"lots,omitempty"`
+}
+
+// ParseCoinSalesCSV reads sales with a date,bitcoin,proceeds header and an
+// optional lots column of receipt dates separated by semicolons. Dates may
+// be mm/dd/yyyy or yyyy-mm-dd.
+func ParseCoinSalesCSV(r io.Reader) ([]CoinSale, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading sales: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error reading sales: empty file")
+	}
+
+	dateCol, btcCol, proceedsCol, lotsCol := -1, -1, -1, -1
+	for i, name := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "date":
+			dateCol = i
+		case "bitcoin", "btc":
+			btcCol = i
+		case "proceeds", "fiat":
+			proceedsCol = i
+		case "lots":
+			lotsCol = i
+		}
+	}
+	if dateCol < 0 || btcCol < 0 || proceedsCol < 0 {
+		return nil, fmt.Errorf("error reading sales: header must have date, bitcoin and proceeds columns")
+	}
+
+	sales := make([]CoinSale, 0, len(records)-1)
+	for line, record := range records[1:] {
+		sale := CoinSale{Date: strings.TrimSpace(record[dateCol])}
+		if sale.Bitcoin, err = strconv.ParseFloat(strings.TrimSpace(record[btcCol]), 64); err != nil {
+			return nil, fmt.Errorf("error reading sales line %d: %w", line+2, err)
+		}
+		if sale.Proceeds, err = strconv.ParseFloat(strings.TrimSpace(record[proceedsCol]), 64); err != nil {
+			return nil, fmt.Errorf("error reading sales line %d: %w", line+2, err)
+		}
+		if lotsCol >= 0 && lotsCol < len(record) {
+			for _, lot := range strings.Split(record[lotsCol], ";") {
+				if lot = strings.TrimSpace(lot); lot != "" {
+					sale.Lots = append(sale.Lots, lot)
+				}
+			}
+		}
+		sales = append(sales, sale)
+	}
+	return sales, nil
+}
+
+// SalesReport is what selling mined coins did over time. HeldData is the
+// bitcoin still held by each date, mined less sold, and CashData the cash
+// position, proceeds received less costs paid. Value is what is held at the
+// report's price plus the proceeds. To compare on equal footing every
+// strategy sold enough on each sale date to get the same cash back;
+// StrategySold is how much bitcoin that took.
+type SalesReport struct {
+	BitcoinSold  float64            `json:"bitcoinSold"`
+	Proceeds     float64            `json:"proceeds"`
+	HeldBitcoin  float64            `json:"heldBitcoin"`
+	Cash         float64            `json:"cash"`
+	Value        float64            `json:"value"`
+	StrategySold map[string]float64 `json:"strategySold"`
+	HeldData     []float64          `json:"heldData,omitempty"`
+	CashData     []float64          `json:"cashData,omitempty"`
+}
+
+// CoinSales returns the request's sales and those in SalesCsv in date order.
+func (r RequestPayload) CoinSales() ([]CoinSale, error) {
+	sales := append([]CoinSale{}, r.Sales...)
+	if strings.TrimSpace(r.SalesCsv) != "" {
+		parsed, err := ParseCoinSalesCSV(strings.NewReader(r.SalesCsv))
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, parsed...)
+	}
+	for _, sale := range sales {
+		if _, err := parseLedgerDate(sale.Date); err != nil {
+			return nil, fmt.Errorf("error parsing sale date %q: %w", sale.Date, err)
+		}
+		if sale.Bitcoin <= 0 || sale.Proceeds < 0 {
+			return nil, fmt.Errorf("sale on %s must have positive bitcoin and proceeds", sale.Date)
+		}
+	}
+	sort.SliceStable(sales, func(i, j int) bool {
+		a, _ := parseLedgerDate(sales[i].Date)
+		b, _ := parseLedgerDate(sales[j].Date)
+		return a.Before(b)
+	})
+	return sales, nil
+}
+
+// ApplySales works out the holdings and cash after sales, in date order, on
+// each of the flows' dates. Like CumulativeMined, a sale between two dates
+// counts on the later one and sales after the last date are left out. mined
+// is the running total mined, and the cash is the proceeds less the flows'
+// outflows, what the strategies bought with. It returns strategies net of
+// what each had to sell at prices, the price on each date, to match the
+// proceeds, never below 0, and leaves the ones passed in as they were.
+func ApplySales(sales []CoinSale, flows CashFlows, prices, mined []float64, strategies []StrategyResult, bitcoinPrice float64) (*SalesReport, []StrategyResult, error) {
+	dates, costs := flows.Dates, flows.Outflows
+	if len(dates) == 0 {
+		return nil, nil, fmt.Errorf("no days to apply the sales to")
+	}
+	soldOn := make([]float64, len(dates))
+	proceedsOn := make([]float64, len(dates))
+	first, last := pricedata.Day(dates[0]), pricedata.Day(dates[len(dates)-1])
+	i := 0
+	for _, sale := range sales {
+		t, _ := parseLedgerDate(sale.Date)
+		day := pricedata.Day(t)
+		if day < first {
+			return nil, nil, fmt.Errorf("sale on %s is before the start date %s", sale.Date, dates[0].Format("01/02/2006"))
+		}
+		if day > last {
+			break
+		}
+		for pricedata.Day(dates[i]) < day {
+			i++
+		}
+		soldOn[i] += sale.Bitcoin
+		proceedsOn[i] += sale.Proceeds
+	}
+
+	report := &SalesReport{StrategySold: map[string]float64{}}
+	paid := 0.0
+	for i := range dates {
+		report.BitcoinSold += soldOn[i]
+		report.Proceeds += proceedsOn[i]
+		if i < len(costs) {
+			paid += costs[i]
+		}
+		held := report.BitcoinSold
+		if i < len(mined) {
+			held = mined[i] - report.BitcoinSold
+		}
+		if held < -1e-9 {
+			return nil, nil, fmt.Errorf("%.8f BTC had been sold by %s, more than the %.8f mined", report.BitcoinSold, dates[i].Format("01/02/2006"), mined[i])
+		}
+		report.HeldData = append(report.HeldData, held)
+		report.CashData = append(report.CashData, report.Proceeds-paid)
+	}
+	report.HeldBitcoin = report.HeldData[len(dates)-1]
+	report.Cash = report.CashData[len(dates)-1]
+	report.Value = report.HeldBitcoin*bitcoinPrice + report.Proceeds
+
+	adjusted := make([]StrategyResult, 0, len(strategies))
+	for _, strategy := range strategies {
+		sold := 0.0
+		data := make([]float64, len(strategy.Data))
+		for i, bought := range strategy.Data {
+			if i < len(dates) && proceedsOn[i] > 0 && i < len(prices) && prices[i] > 0 {
+				sold += math.Min(proceedsOn[i]/prices[i], bought-sold)
+			}
+			data[i] = bought - sold
+		}
+		report.StrategySold[strategy.Name] = sold
+		adjusted = append(adjusted, StrategyResult{Name: strategy.Name, Bitcoin: strategy.Bitcoin - sold, Data: data})
+	}
+	return report, adjusted, nil
+}
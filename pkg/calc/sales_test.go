@@ -0,0 +1,69 @@
+package calc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplySales(t *testing.T) {
+	// 07/03 has no price, so the sale that day counts on 07/04
+	dates := testDates(0, 1, 3, 4)
+	prices := []float64{100, 200, 100, 50}
+	mined := []float64{1, 2, 3, 4}
+	flows := CashFlows{Dates: dates, Days: 5, Spent: 800, Outflows: []float64{500, 100, 100, 100}}
+	strategies := []StrategyResult{
+		{Name: StrategyAmericanHodl, Bitcoin: 8, Data: []float64{8, 8, 8, 8}},
+		{Name: "Small", Bitcoin: 0.2, Data: []float64{0.2, 0.2, 0.2, 0.2}},
+	}
+	sales := []CoinSale{
+		{Date: "07/02/2022", Bitcoin: 0.5, Proceeds: 100},
+		{Date: "2022-07-03", Bitcoin: 0.5, Proceeds: 50},
+		{Date: "07/10/2022", Bitcoin: 1, Proceeds: 1000},
+	}
+
+	report, adjusted, err := ApplySales(sales, flows, prices, mined, strategies, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SalesReport{
+		BitcoinSold:  1,
+		Proceeds:     150,
+		HeldBitcoin:  3,
+		Cash:         -650,
+		Value:        330,
+		HeldData:     []float64{1, 1.5, 2, 3},
+		CashData:     []float64{-500, -500, -550, -650},
+		StrategySold: map[string]float64{StrategyAmericanHodl: 1, "Small": 0.2},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("ApplySales report = %+v, want %+v", report, want)
+	}
+	wantAdjusted := []StrategyResult{
+		{Name: StrategyAmericanHodl, Bitcoin: 7, Data: []float64{8, 7.5, 7, 7}},
+		{Name: "Small", Bitcoin: 0, Data: []float64{0.2, 0, 0, 0}},
+	}
+	if !reflect.DeepEqual(adjusted, wantAdjusted) {
+		t.Fatalf("ApplySales strategies = %+v, want %+v", adjusted, wantAdjusted)
+	}
+	if strategies[0].Bitcoin != 8 || strategies[0].Data[3] != 8 {
+		t.Fatal("ApplySales changed the strategies passed in")
+	}
+
+	errTests := []struct {
+		name  string
+		sales []CoinSale
+	}{
+		{name: "sold more than mined", sales: []CoinSale{{Date: "07/02/2022", Bitcoin: 2.5, Proceeds: 500}}},
+		{name: "before the start", sales: []CoinSale{{Date: "06/30/2022", Bitcoin: 0.1, Proceeds: 10}}},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ApplySales(tt.sales, flows, prices, mined, strategies, 60); err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+	if _, _, err := ApplySales(sales, CashFlows{}, prices, mined, strategies, 60); err == nil {
+		t.Fatal("want an error without dates")
+	}
+}
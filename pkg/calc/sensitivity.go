@@ -0,0 +1,310 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/utils"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+const maxSensitivitySteps = 25
+
+// sensitivityInputs are the request inputs a sensitivity grid can sweep.
+var sensitivityInputs = map[string]func(r *RequestPayload, v float64){
+	"kwhPrice":      func(r *RequestPayload, v float64) { r.KwhPrice = v },
+	"bitcoinPrice":  func(r *RequestPayload, v float64) { r.BitcoinPrice = v },
+	"uptimePercent": func(r *RequestPayload, v float64) { r.UptimePercent = v },
+	"fixedCosts":    func(r *RequestPayload, v float64) { r.FixedCosts = v },
+	"salePrice":     func(r *RequestPayload, v float64) { r.SalePrice = v },
+	"watts":         func(r *RequestPayload, v float64) { r.Watts = v },
+	"bitcoinMined":  func(r *RequestPayload, v float64) { r.BitcoinMined = v },
+	"hashrateTHs":   func(r *RequestPayload, v float64) { r.HashrateTHs = v },
+	"difficultyGrowthPercent": func(r *RequestPayload, v float64) {
+		r.DifficultyGrowthPercent = &v
+	},
+	"priceGrowthPercent": func(r *RequestPayload, v float64) {
+		r.PriceGrowthPercent = &v
+	},
+}
+
+// SensitivityInputs returns the names of the inputs a grid can sweep, sorted.
+func SensitivityInputs() []string {
+	names := make([]string, 0, len(sensitivityInputs))
+	for name := range sensitivityInputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SensitivityAxis sweeps Input from From to To in Steps evenly spaced values.
+type SensitivityAxis struct {
+	Input string  `json:"input"`
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Steps int     `json:"steps"`
+}
+
+// ParseSensitivityAxis reads an axis written as input:from:to:steps, e.g.
+// kwhPrice:0.05:0.20:7.
+func ParseSensitivityAxis(spec string) (SensitivityAxis, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 4 {
+		return SensitivityAxis{}, fmt.Errorf("error parsing axis %q, expected input:from:to:steps", spec)
+	}
+	from, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return SensitivityAxis{}, fmt.Errorf("error parsing axis %q: %w", spec, err)
+	}
+	to, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return SensitivityAxis{}, fmt.Errorf("error parsing axis %q: %w", spec, err)
+	}
+	steps, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return SensitivityAxis{}, fmt.Errorf("error parsing axis %q: %w", spec, err)
+	}
+	return SensitivityAxis{Input: parts[0], From: from, To: to, Steps: steps}, nil
+}
+
+func (a SensitivityAxis) validate() error {
+	if _, ok := sensitivityInputs[a.Input]; !ok {
+		return fmt.Errorf("unknown sensitivity input %q, expected one of %s", a.Input, strings.Join(SensitivityInputs(), ", "))
+	}
+	if a.Steps < 2 || a.Steps > maxSensitivitySteps {
+		return fmt.Errorf("%s steps must be between 2 and %d", a.Input, maxSensitivitySteps)
+	}
+	if a.From == a.To {
+		return fmt.Errorf("%s from and to must differ", a.Input)
+	}
+	return nil
+}
+
+// Values returns the axis' evenly spaced values.
+func (a SensitivityAxis) Values() []float64 {
+	values := make([]float64, 0, a.Steps)
+	for i := 0; i < a.Steps; i++ {
+		values = append(values, a.From+(a.To-a.From)*float64(i)/float64(a.Steps-1))
+	}
+	return values
+}
+
+// SensitivityRequest is a report request plus the two inputs to sweep.
+type SensitivityRequest struct {
+	RequestPayload
+	X SensitivityAxis `json:"x"`
+	Y SensitivityAxis `json:"y"`
+}
+
+// SensitivityCell is the report for one combination of the swept inputs.
+// BreakevenDate assumes today's rate of mining and price carry on;
+// ProjectedBreakevenDate comes from the halving and difficulty aware
+// projection and is empty when it doesn't break even in time.
+type SensitivityCell struct {
+	X                      float64           `json:"x"`
+	Y                      float64           `json:"y"`
+	PercentPaidOff         float64           `json:"percentPaidOff"`
+	BreakevenDate          string            `json:"breakevenDate"`
+	ProjectedBreakevenDate string            `json:"projectedBreakevenDate"`
+	Rankings               []StrategyRanking `json:"rankings"`
+}
+
+// Sensitivity is a grid of reports. Cells[i][j] is for YValues[i] and
+// XValues[j].
+type Sensitivity struct {
+	X            SensitivityAxis     `json:"x"`
+	Y            SensitivityAxis     `json:"y"`
+	XValues      []float64           `json:"xValues"`
+	YValues      []float64           `json:"yValues"`
+	Currency     string              `json:"currency"`
+	BitcoinPrice float64             `json:"bitcoinPrice"`
+	BitcoinMined float64             `json:"bitcoinMined"`
+	Cells        [][]SensitivityCell `json:"cells"`
+}
+
+// Sensitivity runs the report once for the request as given, then again for
+// every combination of the X and Y values. The price and the bitcoin mined
+// are looked up once for the base report and reused by every cell unless an
+// axis sweeps them.
+func (c *Client) Sensitivity(request SensitivityRequest, externalData externaldata.Interface, utils utils.Interface) (*Sensitivity, error) {
+	for _, axis := range []SensitivityAxis{request.X, request.Y} {
+		if err := axis.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if request.X.Input == request.Y.Input {
+		return nil, fmt.Errorf("x and y must sweep different inputs")
+	}
+	electricCostsGiven := request.ElectricCosts != nil && *request.ElectricCosts != 0
+	for _, input := range []string{request.X.Input, request.Y.Input} {
+		if electricCostsGiven && (input == "kwhPrice" || input == "watts" || input == "uptimePercent") {
+			return nil, fmt.Errorf("can't sweep %s when electicCosts is given", input)
+		}
+		if (request.Tariff != nil || request.TariffName != "") && input == "kwhPrice" {
+			return nil, fmt.Errorf("can't sweep kwhPrice when a tariff is given")
+		}
+		if len(request.Machines) > 0 && (input == "watts" || input == "fixedCosts" || input == "hashrateTHs") {
+			return nil, fmt.Errorf("can't sweep %s when machines are given", input)
+		}
+	}
+
+	base := request.RequestPayload
+	base.Simulation = nil
+	stats, err := c.GenerateStats(base, externalData, utils)
+	if err != nil {
+		return nil, err
+	}
+	base.SlushToken = nil
+	base.MinedLedger = nil
+	base.MinedLedgerCsv = ""
+	base.BitcoinMined = stats.BitcoinMined
+	base.BitcoinPrice = stats.BitcoinPrice
+	if base.Pool != nil {
+		// BitcoinMined is already net of the withdrawal fees, so the cells
+		// keep the pool only for the share its fee takes of a listed
+		// hashrate's projection
+		pool := *base.Pool
+		pool.WithdrawalFee, pool.Payouts, pool.Compare = 0, 0, nil
+		base.Pool = &pool
+	}
+
+	sensitivity := &Sensitivity{
+		X:            request.X,
+		Y:            request.Y,
+		XValues:      request.X.Values(),
+		YValues:      request.Y.Values(),
+		Currency:     stats.Currency,
+		BitcoinPrice: stats.BitcoinPrice,
+		BitcoinMined: stats.BitcoinMined,
+	}
+	for _, y := range sensitivity.YValues {
+		row := make([]SensitivityCell, 0, len(sensitivity.XValues))
+		for _, x := range sensitivity.XValues {
+			cellRequest := base
+			sensitivityInputs[request.X.Input](&cellRequest, x)
+			sensitivityInputs[request.Y.Input](&cellRequest, y)
+			cellStats, err := c.GenerateStats(cellRequest, externalData, utils)
+			if err != nil {
+				return nil, fmt.Errorf("error with %s %v, %s %v: %w", request.X.Input, x, request.Y.Input, y, err)
+			}
+			cell := SensitivityCell{
+				X:              x,
+				Y:              y,
+				PercentPaidOff: cellStats.PercentPaidOff,
+				BreakevenDate:  cellStats.ExpectedBreakevenDate,
+				Rankings:       cellStats.Rankings,
+			}
+			if cellStats.Projection != nil {
+				cell.ProjectedBreakevenDate = cellStats.Projection.BreakevenDate
+			}
+			row = append(row, cell)
+		}
+		sensitivity.Cells = append(sensitivity.Cells, row)
+	}
+	return sensitivity, nil
+}
+
+// WriteCSV writes one row per cell, with a column per strategy ranking.
+func (s *Sensitivity) WriteCSV(w io.Writer) error {
+	strategies := []string{}
+	if len(s.Cells) > 0 && len(s.Cells[0]) > 0 {
+		for _, ranking := range s.Cells[0][0].Rankings {
+			strategies = append(strategies, ranking.Name)
+		}
+		sort.Strings(strategies)
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{s.X.Input, s.Y.Input, "percentPaidOff", "breakevenDate", "projectedBreakevenDate"}
+	for _, name := range strategies {
+		header = append(header, name)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range s.Cells {
+		for _, cell := range row {
+			record := []string{
+				strconv.FormatFloat(cell.X, 'f', -1, 64),
+				strconv.FormatFloat(cell.Y, 'f', -1, 64),
+				strconv.FormatFloat(cell.PercentPaidOff, 'f', 2, 64),
+				cell.BreakevenDate,
+				cell.ProjectedBreakevenDate,
+			}
+			percents := make(map[string]float64, len(cell.Rankings))
+			for _, ranking := range cell.Rankings {
+				percents[ranking.Name] = ranking.Percent
+			}
+			for _, name := range strategies {
+				record = append(record, strconv.FormatFloat(percents[name], 'f', 2, 64))
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sensitivityGrid lets a Sensitivity be drawn as a heatmap of percent paid
+// off.
+type sensitivityGrid struct {
+	s *Sensitivity
+}
+
+func (g sensitivityGrid) Dims() (int, int)   { return len(g.s.XValues), len(g.s.YValues) }
+func (g sensitivityGrid) Z(c, r int) float64 { return g.s.Cells[r][c].PercentPaidOff }
+func (g sensitivityGrid) X(c int) float64    { return g.s.XValues[c] }
+func (g sensitivityGrid) Y(r int) float64    { return g.s.YValues[r] }
+
+// MakeHeatmap draws the grid's percent paid off as a heatmap, red where
+// mining is furthest from paying off and blue where it is furthest past it,
+// with each cell labelled.
+func (c *Client) MakeHeatmap(s *Sensitivity) (*string, error) {
+	p := plot.New()
+	p.Title.Text = "Percent Paid Off"
+	p.X.Label.Text = s.X.Input
+	p.Y.Label.Text = s.Y.Input
+
+	grid := sensitivityGrid{s}
+	colors := moreland.SmoothBlueRed()
+	colors.SetMin(0)
+	colors.SetMax(1)
+	p.Add(plotter.NewHeatMap(grid, palette.Reverse(colors).Palette(255)))
+
+	labels := plotter.XYLabels{}
+	for r, row := range s.Cells {
+		for col, cell := range row {
+			labels.XYs = append(labels.XYs, plotter.XY{X: grid.X(col), Y: grid.Y(r)})
+			labels.Labels = append(labels.Labels, fmt.Sprintf("%.0f%%", cell.PercentPaidOff))
+		}
+	}
+	cellLabels, err := plotter.NewLabels(labels)
+	if err != nil {
+		return nil, fmt.Errorf("error making heatmap: %w", err)
+	}
+	for i := range cellLabels.TextStyle {
+		cellLabels.TextStyle[i].XAlign = -0.5
+		cellLabels.TextStyle[i].YAlign = -0.5
+		cellLabels.TextStyle[i].Font.Size = vg.Points(7)
+	}
+	p.Add(cellLabels)
+
+	fileName := fmt.Sprintf("%d-sensitivity.png", time.Now().UnixNano())
+	if err := p.Save(6*vg.Inch, 5*vg.Inch, fileName); err != nil {
+		return nil, fmt.Errorf("error saving heatmap: %w", err)
+	}
+	return &fileName, nil
+}
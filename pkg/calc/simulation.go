@@ -0,0 +1,302 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+const (
+	SimulationGBM       = "gbm"
+	SimulationBootstrap = "bootstrap"
+
+	defaultSimulationPaths = 1000
+	maxSimulationPaths     = 10000
+	defaultBlockDays       = 30
+	// fanStepDays is how often the fan chart bands are sampled, which keeps
+	// the memory a large run needs down.
+	fanStepDays = 7
+)
+
+var simulationPercentiles = []float64{10, 25, 50, 75, 90}
+
+// SimulationRequest asks for a Monte Carlo breakeven forecast. Model is gbm
+// (the default), geometric brownian motion with drift and volatility
+// estimated from the price history, or bootstrap, which replays blocks of
+// BlockDays consecutive historical daily returns picked at random. A Seed of
+// 0 picks one; the one used is returned so the run can be repeated.
+type SimulationRequest struct {
+	Model     string `json:"model"`
+	Paths     int    `json:"paths"`
+	Seed      int64  `json:"seed"`
+	BlockDays int    `json:"blockDays"`
+}
+
+// FanBand is the spread of one day across the simulated paths.
+type FanBand struct {
+	Day  int     `json:"day"`
+	Date string  `json:"date"`
+	P10  float64 `json:"p10"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P90  float64 `json:"p90"`
+}
+
+// FanChart shows, every fanStepDays, the bitcoin the costs paid so far would
+// buy at each simulated price, against the bitcoin projected to be mined by
+// then. Mining has broken even on a path once Mined is above its value.
+type FanChart struct {
+	Mined []float64 `json:"mined"`
+	Bands []FanBand `json:"bands"`
+}
+
+// Simulation is the result of a Monte Carlo breakeven forecast. Drift and
+// Volatility are yearly, of log returns. BreakevenPercentiles maps p10 to p90
+// to the date that share of paths had broken even by, empty when it is past
+// the projected days.
+type Simulation struct {
+	Model                       string            `json:"model"`
+	Paths                       int               `json:"paths"`
+	Seed                        int64             `json:"seed"`
+	BlockDays                   int               `json:"blockDays,omitempty"`
+	Drift                       float64           `json:"drift"`
+	Volatility                  float64           `json:"volatility"`
+	BreakevenProbability6Months float64           `json:"breakevenProbability6Months"`
+	BreakevenProbability1Year   float64           `json:"breakevenProbability1Year"`
+	BreakevenProbability2Years  float64           `json:"breakevenProbability2Years"`
+	BreakevenPercentiles        map[string]string `json:"breakevenPercentiles"`
+	Fan                         FanChart          `json:"fan"`
+}
+
+// Simulate runs the projection a once per path, each with its own price path
+// starting from a.Price. Mining and costs are the same on every path; only
+// the price changes. history is the daily prices the returns are drawn from,
+// read from their field.
+func (c *Client) Simulate(request SimulationRequest, a ProjectionAssumptions, history []pricedata.PricePoint, field string) (*Simulation, error) {
+	model := strings.ToLower(request.Model)
+	if model == "" {
+		model = SimulationGBM
+	}
+	if model != SimulationGBM && model != SimulationBootstrap {
+		return nil, fmt.Errorf("unknown simulation model %q, expected %s or %s", request.Model, SimulationGBM, SimulationBootstrap)
+	}
+	paths := request.Paths
+	if paths <= 0 {
+		paths = defaultSimulationPaths
+	}
+	if paths > maxSimulationPaths {
+		return nil, fmt.Errorf("simulation paths must be at most %d", maxSimulationPaths)
+	}
+	returns := logReturns(history, field)
+	if len(returns) < 2 {
+		return nil, fmt.Errorf("not enough price history to simulate from")
+	}
+	seed := request.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	drift, volatility := meanStdDev(returns)
+	simulation := &Simulation{
+		Model:                model,
+		Paths:                paths,
+		Seed:                 seed,
+		Drift:                drift * 365,
+		Volatility:           volatility * math.Sqrt(365),
+		BreakevenPercentiles: map[string]string{},
+	}
+	rng := rand.New(rand.NewSource(seed))
+	next := func() float64 {
+		return drift + volatility*rng.NormFloat64()
+	}
+	newPath := func() {}
+	if model == SimulationBootstrap {
+		blockDays := request.BlockDays
+		if blockDays <= 0 {
+			blockDays = defaultBlockDays
+		}
+		if blockDays > len(returns) {
+			blockDays = len(returns)
+		}
+		simulation.BlockDays = blockDays
+		block, i := 0, blockDays
+		newPath = func() { i = blockDays }
+		next = func() float64 {
+			if i == blockDays {
+				block, i = rng.Intn(len(returns)-blockDays+1), 0
+			}
+			i++
+			return returns[block+i-1]
+		}
+	}
+
+	_, mined, costs := projectMining(a)
+	never := a.Days + 1
+	breakevens := make([]int, 0, paths)
+	bands := make([][]float64, a.Days/fanStepDays)
+	for p := 0; p < paths; p++ {
+		newPath()
+		price := a.Price
+		breakeven := never
+		if a.BitcoinMined*a.Price >= a.Costs {
+			breakeven = 0
+		}
+		for day := 1; day <= a.Days; day++ {
+			price *= math.Exp(next())
+			if breakeven == never && mined[day-1]*price >= costs[day-1] {
+				breakeven = day
+			}
+			if day%fanStepDays == 0 {
+				bands[day/fanStepDays-1] = append(bands[day/fanStepDays-1], costs[day-1]/price)
+			}
+		}
+		breakevens = append(breakevens, breakeven)
+	}
+
+	sort.Ints(breakevens)
+	byDays := func(days int) float64 {
+		n := sort.Search(len(breakevens), func(i int) bool { return breakevens[i] > days })
+		return float64(n) / float64(len(breakevens)) * 100
+	}
+	simulation.BreakevenProbability6Months = byDays(daysUntil(a.Start, 6))
+	simulation.BreakevenProbability1Year = byDays(daysUntil(a.Start, 12))
+	simulation.BreakevenProbability2Years = byDays(daysUntil(a.Start, 24))
+	for _, pct := range simulationPercentiles {
+		date := ""
+		if day := breakevens[percentileIndex(len(breakevens), pct)]; day != never {
+			date = a.Start.AddDate(0, 0, day).Format("01/02/2006")
+		}
+		simulation.BreakevenPercentiles[fmt.Sprintf("p%.0f", pct)] = date
+	}
+
+	simulation.Fan = FanChart{Mined: []float64{}, Bands: []FanBand{}}
+	for i, values := range bands {
+		day := (i + 1) * fanStepDays
+		sort.Float64s(values)
+		at := func(pct float64) float64 { return values[percentileIndex(len(values), pct)] }
+		simulation.Fan.Mined = append(simulation.Fan.Mined, mined[day-1])
+		simulation.Fan.Bands = append(simulation.Fan.Bands, FanBand{
+			Day:  day,
+			Date: a.Start.AddDate(0, 0, day).Format("01/02/2006"),
+			P10:  at(10),
+			P25:  at(25),
+			P50:  at(50),
+			P75:  at(75),
+			P90:  at(90),
+		})
+	}
+	return simulation, nil
+}
+
+// AddFanChart draws fan onto p after the history, which takes up the first
+// offset days of the x axis: the P10-P90 and P25-P75 bands, the median and
+// the projected Mined line.
+func AddFanChart(p *plot.Plot, offset int, fan *FanChart) error {
+	if fan == nil || len(fan.Bands) == 0 {
+		return nil
+	}
+	band := func(low, high func(b FanBand) float64, fill color.Color) (*plotter.Polygon, error) {
+		pts := make(plotter.XYs, 0, 2*len(fan.Bands))
+		for _, b := range fan.Bands {
+			pts = append(pts, plotter.XY{X: float64(offset + b.Day), Y: high(b)})
+		}
+		for i := len(fan.Bands) - 1; i >= 0; i-- {
+			pts = append(pts, plotter.XY{X: float64(offset + fan.Bands[i].Day), Y: low(fan.Bands[i])})
+		}
+		poly, err := plotter.NewPolygon(pts)
+		if err != nil {
+			return nil, err
+		}
+		poly.Color = fill
+		poly.LineStyle.Width = 0
+		return poly, nil
+	}
+	outer, err := band(func(b FanBand) float64 { return b.P10 }, func(b FanBand) float64 { return b.P90 }, color.NRGBA{R: 70, G: 130, B: 180, A: 60})
+	if err != nil {
+		return fmt.Errorf("error making fan chart: %w", err)
+	}
+	inner, err := band(func(b FanBand) float64 { return b.P25 }, func(b FanBand) float64 { return b.P75 }, color.NRGBA{R: 70, G: 130, B: 180, A: 120})
+	if err != nil {
+		return fmt.Errorf("error making fan chart: %w", err)
+	}
+
+	median := make(plotter.XYs, len(fan.Bands))
+	mined := make(plotter.XYs, len(fan.Bands))
+	for i, b := range fan.Bands {
+		median[i] = plotter.XY{X: float64(offset + b.Day), Y: b.P50}
+		mined[i] = plotter.XY{X: float64(offset + b.Day), Y: fan.Mined[i]}
+	}
+	medianLine, err := plotter.NewLine(median)
+	if err != nil {
+		return fmt.Errorf("error making fan chart: %w", err)
+	}
+	medianLine.Color = color.NRGBA{R: 70, G: 130, B: 180, A: 255}
+	minedLine, err := plotter.NewLine(mined)
+	if err != nil {
+		return fmt.Errorf("error making fan chart: %w", err)
+	}
+	minedLine.Color = color.Black
+	minedLine.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+
+	p.Add(outer, inner, medianLine, minedLine)
+	p.Legend.Top = true
+	p.Legend.Add("Costs in BTC P10-P90", outer)
+	p.Legend.Add("Costs in BTC P25-P75", inner)
+	p.Legend.Add("Projected Mined", minedLine)
+	return nil
+}
+
+// percentileIndex returns the index of the pct percentile in n sorted values.
+func percentileIndex(n int, pct float64) int {
+	i := int(math.Ceil(pct/100*float64(n))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// daysUntil returns how many days from start until the same day months later.
+func daysUntil(start time.Time, months int) int {
+	return int(start.AddDate(0, months, 0).Sub(start).Hours() / 24)
+}
+
+// logReturns returns the daily log returns of points. A return is only taken
+// between consecutive days, so a gap in the price data doesn't put several
+// days' move into one day's return.
+func logReturns(points []pricedata.PricePoint, field string) []float64 {
+	returns := make([]float64, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		if pricedata.Day(points[i].Time())-pricedata.Day(points[i-1].Time()) != 1 {
+			continue
+		}
+		before, after := points[i-1].Price(field), points[i].Price(field)
+		if before > 0 && after > 0 {
+			returns = append(returns, math.Log(after/before))
+		}
+	}
+	return returns
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,22 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLogReturns(t *testing.T) {
+	// 07/02 is missing and 07/05 has no price
+	points := []pricedata.PricePoint{}
+	for i, offset := range []int{0, 2, 3, 4, 5} {
+		price := []float64{100, 400, 200, 0, 100}[i]
+		points = append(points, pricedata.PricePoint{Timestamp: testDates(offset)[0].Unix(), OpenPrice: price, ClosePrice: price * 2})
+	}
+	for _, field := range []string{pricedata.FieldOpen, pricedata.FieldClose} {
+		if got, want := logReturns(points, field), []float64{math.Log(0.5)}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("logReturns of the %s = %v, want only the 07/03 to 07/04 return %v", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StrategyAmericanHodl = "AmericanHodl"
+	StrategyDailyDCA     = "Daily-DCA"
+	StrategyAntiMiner    = "Anti-Miner"
+)
+
+// CashFlows is the money a mining operation put in, for a strategy to buy
+// bitcoin with instead. Spent is the total, net of hardware sold, over Days
+// days; Outflows is what was paid out on each of Dates, less what came back.
+type CashFlows struct {
+	Dates    []time.Time
+	Days     float64
+	Spent    float64
+	Outflows []float64
+}
+
+// OperationCashFlows builds the cash flows of spent over days, paid out on
+// dates as a fleet's outflows, or else fixedCosts on the first date and
+// electricCosts spread evenly over the calendar days, each date paying for
+// the days since the one before. salePrice, hardware sold outside a fleet,
+// comes back on the last date, so the outflows add up to spent.
+func OperationCashFlows(fleet *Fleet, dates []time.Time, spent, fixedCosts, electricCosts, salePrice, days float64) CashFlows {
+	flows := CashFlows{Dates: dates, Days: days, Spent: spent}
+	if fleet != nil {
+		flows.Outflows = fleet.OutflowsOn(dates)
+	} else if len(dates) > 0 {
+		covered := float64(pricedata.Day(dates[len(dates)-1]) - pricedata.Day(dates[0]) + 1)
+		for i, date := range dates {
+			daysPaid := 1.0
+			if i > 0 {
+				daysPaid = float64(pricedata.Day(date) - pricedata.Day(dates[i-1]))
+			}
+			flows.Outflows = append(flows.Outflows, electricCosts*daysPaid/covered)
+		}
+		flows.Outflows[0] += fixedCosts
+	}
+	if len(flows.Outflows) > 0 {
+		flows.Outflows[len(flows.Outflows)-1] -= salePrice
+	}
+	return flows
+}
+
+// Strategy is a way of buying bitcoin with what mining cost. Buy returns the
+// running total of bitcoin held on each of the cash flows' dates, buying at
+// prices, the price on each.
+type Strategy interface {
+	Name() string
+	Buy(flows CashFlows, prices []float64) []float64
+}
+
+// StrategyResult is the bitcoin a strategy held on each date, Data, and on
+// the last, Bitcoin.
+type StrategyResult struct {
+	Name    string    `json:"name"`
+	Bitcoin float64   `json:"bitcoin"`
+	Data    []float64 `json:"data,omitempty"`
+}
+
+// strategies are the registered strategies, in the order they are charted.
+// strategiesMu guards them, since requests read them while a strategy may
+// still be registering.
+var (
+	strategiesMu sync.RWMutex
+	strategies   = []Strategy{americanHodl{}, dailyDCA{}, antiMiner{}}
+)
+
+// RegisterStrategy adds s. A name that is already registered, in any case,
+// is an error.
+func RegisterStrategy(s Strategy) error {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	for _, registered := range strategies {
+		if strings.EqualFold(registered.Name(), s.Name()) {
+			return fmt.Errorf("strategy %s is already registered", s.Name())
+		}
+	}
+	strategies = append(strategies, s)
+	return nil
+}
+
+// StrategyNames returns the names of the registered strategies.
+func StrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for _, s := range strategies {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// LookupStrategy returns the registered strategy called name, in any case.
+func LookupStrategy(name string) (Strategy, error) {
+	strategiesMu.RLock()
+	for _, s := range strategies {
+		if strings.EqualFold(s.Name(), strings.TrimSpace(name)) {
+			strategiesMu.RUnlock()
+			return s, nil
+		}
+	}
+	strategiesMu.RUnlock()
+	return nil, fmt.Errorf("unknown strategy %q, expected one of %s", name, strings.Join(StrategyNames(), ", "))
+}
+
+// RunStrategies runs the strategies named in names, or every registered one
+// when names is empty, in that order.
+func RunStrategies(names []string, flows CashFlows, prices []float64) ([]StrategyResult, error) {
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no prices to buy at")
+	}
+	if len(names) == 0 {
+		names = StrategyNames()
+	}
+	results := make([]StrategyResult, 0, len(names))
+	for _, name := range names {
+		s, err := LookupStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result.Name == s.Name() {
+				return nil, fmt.Errorf("strategy %s is listed twice", s.Name())
+			}
+		}
+		data := s.Buy(flows, prices)
+		result := StrategyResult{Name: s.Name(), Data: data}
+		if len(data) > 0 {
+			result.Bitcoin = data[len(data)-1]
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// StrategyRanking is how much more (positive) or less bitcoin, in percent,
+// a strategy held than mining did.
+type StrategyRanking struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+// StrategyRankings returns the results, without their daily data, from the
+// most bitcoin held to the least, by name when two held the same.
+func StrategyRankings(results []StrategyResult) []StrategyResult {
+	rankings := make([]StrategyResult, 0, len(results))
+	for _, result := range results {
+		rankings = append(rankings, StrategyResult{Name: result.Name, Bitcoin: result.Bitcoin})
+	}
+	sort.SliceStable(rankings, func(i, j int) bool {
+		if rankings[i].Bitcoin != rankings[j].Bitcoin {
+			return rankings[i].Bitcoin > rankings[j].Bitcoin
+		}
+		return rankings[i].Name < rankings[j].Name
+	})
+	return rankings
+}
+
+// americanHodl buys with everything on the first day.
+type americanHodl struct{}
+
+func (americanHodl) Name() string { return StrategyAmericanHodl }
+
+func (americanHodl) Buy(flows CashFlows, prices []float64) []float64 {
+	bitcoinAcquired := flows.Spent / prices[0]
+	cumulativeTotal := make([]float64, 0, len(prices))
+	for range prices {
+		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
+	}
+	return cumulativeTotal
+}
+
+// dailyDCA spreads what was spent evenly over the days mined.
+type dailyDCA struct{}
+
+func (dailyDCA) Name() string { return StrategyDailyDCA }
+
+func (dailyDCA) Buy(flows CashFlows, prices []float64) []float64 {
+	dollarsToSpendPerDay := flows.Spent / flows.Days
+	bitcoinAcquired := 0.0
+	cumulativeTotal := make([]float64, 0, len(prices))
+	for _, val := range prices {
+		bitcoinAcquired += dollarsToSpendPerDay / val
+		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
+	}
+	return cumulativeTotal
+}
+
+// antiMiner buys, on each day, bitcoin worth what the miner paid out that
+// day, and sells bitcoin worth what came back, such as hardware resold.
+type antiMiner struct{}
+
+func (antiMiner) Name() string { return StrategyAntiMiner }
+
+func (antiMiner) Buy(flows CashFlows, prices []float64) []float64 {
+	bitcoinAcquired := 0.0
+	cumulativeTotal := make([]float64, 0, len(prices))
+	for i, val := range prices {
+		if i < len(flows.Outflows) {
+			bitcoinAcquired += flows.Outflows[i] / val
+		}
+		cumulativeTotal = append(cumulativeTotal, bitcoinAcquired)
+	}
+	return cumulativeTotal
+}
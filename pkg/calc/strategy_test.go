@@ -0,0 +1,136 @@
+package calc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDates returns the days from 07/01/2022 at the given offsets.
+func testDates(offsets ...int) []time.Time {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	dates := make([]time.Time, 0, len(offsets))
+	for _, offset := range offsets {
+		dates = append(dates, start.AddDate(0, 0, offset))
+	}
+	return dates
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func TestOperationCashFlows(t *testing.T) {
+	daily := testDates(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	gaps := testDates(0, 1, 4, 5, 9)
+	fleet, err := FleetSchedule([]Machine{
+		{Model: "S9", PurchaseDate: "07/01/2022", PurchasePrice: 1000, Watts: 1400, HashrateTHs: 14, DecommissionDate: "07/06/2022", ResalePrice: 400},
+		{Model: "S19", PurchaseDate: "07/03/2022", PurchasePrice: 3000, Watts: 3250, HashrateTHs: 95},
+	}, daily[0], daily[9], 0.1, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleetSpent := fleet.Purchases - fleet.Resales + fleet.ElectricCosts
+
+	tests := []struct {
+		name                                 string
+		fleet                                *Fleet
+		dates                                []time.Time
+		fixedCosts, electricCosts, salePrice float64
+		spent                                float64
+		first, last                          float64
+	}{
+		{name: "daily", dates: daily, fixedCosts: 3000, electricCosts: 100, spent: 3100, first: 3010, last: 10},
+		{name: "missing price days", dates: gaps, fixedCosts: 3000, electricCosts: 100, spent: 3100, first: 3010, last: 40},
+		{name: "hardware sold", dates: daily, fixedCosts: 3000, electricCosts: 100, salePrice: 1200, spent: 1900, first: 3010, last: -1190},
+		{name: "fleet", fleet: fleet, dates: daily, spent: fleetSpent, first: fleet.Days[0].Outflow(), last: fleet.Days[9].Outflow()},
+		{name: "fleet on missing price days", fleet: fleet, dates: gaps, spent: fleetSpent, first: fleet.Days[0].Outflow(), last: sum([]float64{
+			fleet.Days[6].Outflow(), fleet.Days[7].Outflow(), fleet.Days[8].Outflow(), fleet.Days[9].Outflow(),
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flows := OperationCashFlows(tt.fleet, tt.dates, tt.spent, tt.fixedCosts, tt.electricCosts, tt.salePrice, 10)
+			if len(flows.Outflows) != len(tt.dates) {
+				t.Fatalf("got %d outflows for %d dates", len(flows.Outflows), len(tt.dates))
+			}
+			if got := sum(flows.Outflows); math.Abs(got-flows.Spent) > 1e-9 {
+				t.Fatalf("outflows add up to %v, want Spent %v", got, flows.Spent)
+			}
+			if got := flows.Outflows[0]; math.Abs(got-tt.first) > 1e-9 {
+				t.Fatalf("first outflow = %v, want %v", got, tt.first)
+			}
+			if got := flows.Outflows[len(flows.Outflows)-1]; math.Abs(got-tt.last) > 1e-9 {
+				t.Fatalf("last outflow = %v, want %v", got, tt.last)
+			}
+		})
+	}
+}
+
+func TestRunStrategies(t *testing.T) {
+	dates := testDates(0, 1, 2, 3)
+	prices := []float64{100, 200, 50, 100}
+	// 400 up front and 100 a day
+	flows := CashFlows{Dates: dates, Days: 4, Spent: 800, Outflows: []float64{500, 100, 100, 100}}
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []StrategyResult
+		wantErr bool
+	}{
+		{
+			name:  "every strategy in order",
+			names: nil,
+			want: []StrategyResult{
+				{Name: StrategyAmericanHodl, Bitcoin: 8, Data: []float64{8, 8, 8, 8}},
+				{Name: StrategyDailyDCA, Bitcoin: 9, Data: []float64{2, 3, 7, 9}},
+				{Name: StrategyAntiMiner, Bitcoin: 8.5, Data: []float64{5, 5.5, 7.5, 8.5}},
+			},
+		},
+		{
+			name:  "picked by name in any case",
+			names: []string{"anti-miner", " americanhodl "},
+			want: []StrategyResult{
+				{Name: StrategyAntiMiner, Bitcoin: 8.5, Data: []float64{5, 5.5, 7.5, 8.5}},
+				{Name: StrategyAmericanHodl, Bitcoin: 8, Data: []float64{8, 8, 8, 8}},
+			},
+		},
+		{name: "unknown strategy", names: []string{"Lottery"}, wantErr: true},
+		{name: "listed twice", names: []string{"Daily-DCA", "daily-dca"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RunStrategies(tt.names, flows, prices)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunStrategies err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("RunStrategies = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := RunStrategies(nil, flows, nil); err == nil {
+		t.Fatal("want an error without prices")
+	}
+}
+
+func TestStrategyRankings(t *testing.T) {
+	results := []StrategyResult{
+		{Name: "b", Bitcoin: 1, Data: []float64{1}},
+		{Name: "c", Bitcoin: 2},
+		{Name: "a", Bitcoin: 1},
+	}
+	want := []StrategyResult{{Name: "c", Bitcoin: 2}, {Name: "a", Bitcoin: 1}, {Name: "b", Bitcoin: 1}}
+	if got := StrategyRankings(results); !reflect.DeepEqual(got, want) {
+		t.Fatalf("StrategyRankings = %+v, want %+v", got, want)
+	}
+	if results[0].Data == nil {
+		t.Fatal("StrategyRankings changed its input")
+	}
+}
@@ -0,0 +1,291 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Tariff is an electricity rate schedule. Each day's energy is priced by the
+// season its month falls in; FixedMonthly and the demand charge, DemandPerKw
+// times the month's peak kW, are spread evenly over the days of the month.
+type Tariff struct {
+	Name         string         `json:"name" yaml:"name"`
+	FixedMonthly float64        `json:"fixedMonthly" yaml:"fixedMonthly"`
+	DemandPerKw  float64        `json:"demandPerKw" yaml:"demandPerKw"`
+	Seasons      []TariffSeason `json:"seasons" yaml:"seasons"`
+}
+
+// TariffSeason prices energy in Months (1-12, every month when empty). A kWh
+// costs Price, or with Tiers the price of the block the month's usage so far
+// has reached. Hours inside one of Periods cost that period's price instead,
+// which makes a time of use tariff.
+type TariffSeason struct {
+	Name    string         `json:"name" yaml:"name"`
+	Months  []int          `json:"months" yaml:"months"`
+	Price   float64        `json:"price" yaml:"price"`
+	Tiers   []TariffTier   `json:"tiers" yaml:"tiers"`
+	Periods []TariffPeriod `json:"periods" yaml:"periods"`
+}
+
+// TariffTier prices a month's usage up to UpToKwh, counted from the start of
+// the month. The last tier leaves UpToKwh at 0 to price everything above.
+type TariffTier struct {
+	UpToKwh float64 `json:"upToKwh" yaml:"upToKwh"`
+	Price   float64 `json:"price" yaml:"price"`
+}
+
+// TariffPeriod prices the hours from StartHour up to EndHour, wrapping past
+// midnight when EndHour is the smaller, e.g. 22 to 6.
+type TariffPeriod struct {
+	Name         string  `json:"name" yaml:"name"`
+	StartHour    int     `json:"startHour" yaml:"startHour"`
+	EndHour      int     `json:"endHour" yaml:"endHour"`
+	Price        float64 `json:"price" yaml:"price"`
+	WeekdaysOnly bool    `json:"weekdaysOnly" yaml:"weekdaysOnly"`
+}
+
+// MonthlyBill is one month of a tariff's charges. Days is how many of the
+// month's days the report covers.
+type MonthlyBill struct {
+	Month  string  `json:"month"`
+	Days   int     `json:"days"`
+	Kwh    float64 `json:"kwh"`
+	PeakKw float64 `json:"peakKw"`
+	Energy float64 `json:"energy"`
+	Fixed  float64 `json:"fixed"`
+	Demand float64 `json:"demand"`
+	Total  float64 `json:"total"`
+}
+
+// LoadTariff reads a tariff from a YAML or JSON file.
+func LoadTariff(path string) (*Tariff, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tariff %s: %w", path, err)
+	}
+	var tariff Tariff
+	if err := yaml.Unmarshal(content, &tariff); err != nil {
+		return nil, fmt.Errorf("error parsing tariff %s: %w", path, err)
+	}
+	if err := tariff.Validate(); err != nil {
+		return nil, fmt.Errorf("error with tariff %s: %w", path, err)
+	}
+	return &tariff, nil
+}
+
+// Validate checks every month has exactly one season and that the tiers and
+// periods make sense.
+func (t *Tariff) Validate() error {
+	if len(t.Seasons) == 0 {
+		return fmt.Errorf("tariff has no seasons")
+	}
+	if t.FixedMonthly < 0 || t.DemandPerKw < 0 {
+		return fmt.Errorf("tariff fixedMonthly and demandPerKw can't be negative")
+	}
+	for month := time.January; month <= time.December; month++ {
+		found := 0
+		for _, season := range t.Seasons {
+			if season.covers(month) {
+				found++
+			}
+		}
+		if found != 1 {
+			return fmt.Errorf("tariff has %d seasons for %s, expected 1", found, month)
+		}
+	}
+	for _, season := range t.Seasons {
+		for _, month := range season.Months {
+			if month < 1 || month > 12 {
+				return fmt.Errorf("tariff season %s has month %d, expected 1 to 12", season.Name, month)
+			}
+		}
+		for i, tier := range season.Tiers {
+			last := i == len(season.Tiers)-1
+			if !last && (tier.UpToKwh <= 0 || (i > 0 && tier.UpToKwh <= season.Tiers[i-1].UpToKwh)) {
+				return fmt.Errorf("tariff season %s tiers must have increasing upToKwh", season.Name)
+			}
+			if last && tier.UpToKwh != 0 {
+				return fmt.Errorf("tariff season %s's last tier must leave upToKwh at 0 to price everything above", season.Name)
+			}
+		}
+		for _, period := range season.Periods {
+			if period.StartHour < 0 || period.StartHour > 23 || period.EndHour < 0 || period.EndHour > 24 || period.StartHour == period.EndHour {
+				return fmt.Errorf("tariff season %s period %s must run between two different hours from 0 to 24", season.Name, period.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (s TariffSeason) covers(month time.Month) bool {
+	if len(s.Months) == 0 {
+		return true
+	}
+	for _, m := range s.Months {
+		if time.Month(m) == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tariff) season(month time.Month) TariffSeason {
+	for _, season := range t.Seasons {
+		if season.covers(month) {
+			return season
+		}
+	}
+	return TariffSeason{}
+}
+
+// periodPrice returns the price of the period hour falls in on date, if any.
+func (s TariffSeason) periodPrice(date time.Time, hour int) (float64, bool) {
+	weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+	for _, period := range s.Periods {
+		if period.WeekdaysOnly && weekend {
+			continue
+		}
+		in := hour >= period.StartHour && hour < period.EndHour
+		if period.EndHour < period.StartHour {
+			in = hour >= period.StartHour || hour < period.EndHour
+		}
+		if in {
+			return period.Price, true
+		}
+	}
+	return 0, false
+}
+
+// energyCost prices kwh used once the month's usage had reached used.
+func (s TariffSeason) energyCost(used, kwh float64) float64 {
+	if len(s.Tiers) == 0 {
+		return kwh * s.Price
+	}
+	cost := 0.0
+	for _, tier := range s.Tiers {
+		if kwh <= 0 {
+			break
+		}
+		if tier.UpToKwh != 0 && used >= tier.UpToKwh {
+			continue
+		}
+		inTier := kwh
+		if tier.UpToKwh != 0 {
+			inTier = math.Min(kwh, tier.UpToKwh-used)
+		}
+		cost += inTier * tier.Price
+		used += inTier
+		kwh -= inTier
+	}
+	return cost
+}
+
+// DailyCosts bills a load of watts[i] on the i-th day from start, drawn
+// evenly around the clock at uptimePercent. It returns each day's cost,
+// including its share of the month's fixed and demand charges, and the
+// monthly bills. The peak is the full watts of the busiest day, since a
+// miner that is up draws all of it.
+func (t *Tariff) DailyCosts(start time.Time, watts []float64, uptimePercent float64) ([]float64, []MonthlyBill, error) {
+	if err := t.Validate(); err != nil {
+		return nil, nil, err
+	}
+	costs := make([]float64, len(watts))
+	bills := []MonthlyBill{}
+	billOf := make([]int, len(watts))
+	for i, w := range watts {
+		date := start.AddDate(0, 0, i)
+		month := date.Format("01/2006")
+		if len(bills) == 0 || bills[len(bills)-1].Month != month {
+			bills = append(bills, MonthlyBill{Month: month})
+		}
+		bill := &bills[len(bills)-1]
+		billOf[i] = len(bills) - 1
+
+		season := t.season(date.Month())
+		perHour := w / 1000 * uptimePercent / 100
+		energy := 0.0
+		for hour := 0; hour < 24; hour++ {
+			if price, ok := season.periodPrice(date, hour); ok {
+				energy += perHour * price
+			} else {
+				energy += season.energyCost(bill.Kwh, perHour)
+			}
+			bill.Kwh += perHour
+		}
+		costs[i] = energy
+		bill.Energy += energy
+		bill.Days++
+		bill.PeakKw = math.Max(bill.PeakKw, w/1000)
+	}
+
+	for i := range watts {
+		bill := &bills[billOf[i]]
+		date := start.AddDate(0, 0, i)
+		daysInMonth := float64(time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+		fixed := t.FixedMonthly / daysInMonth
+		demand := bill.PeakKw * t.DemandPerKw / daysInMonth
+		costs[i] += fixed + demand
+		bill.Fixed += fixed
+		bill.Demand += demand
+	}
+	for i := range bills {
+		bills[i].Total = bills[i].Energy + bills[i].Fixed + bills[i].Demand
+	}
+	return costs, bills, nil
+}
+
+// Bill prices a steady load of watts at uptimePercent every day from start
+// through end and returns the total and the monthly bills.
+func (t *Tariff) Bill(start, end time.Time, watts, uptimePercent float64) (float64, []MonthlyBill, error) {
+	days := pricedata.Day(end) - pricedata.Day(start) + 1
+	if days <= 0 {
+		return 0, nil, fmt.Errorf("end %s is before start %s", end.Format("01/02/2006"), start.Format("01/02/2006"))
+	}
+	loads := make([]float64, days)
+	for i := range loads {
+		loads[i] = watts
+	}
+	costs, bills, err := t.DailyCosts(start, loads, uptimePercent)
+	if err != nil {
+		return 0, nil, err
+	}
+	total := 0.0
+	for _, cost := range costs {
+		total += cost
+	}
+	return total, bills, nil
+}
+
+// requestTariff returns the tariff a request gives inline or names from the
+// config, or nil when it has none.
+func (c *Client) requestTariff(requestPayload RequestPayload) (*Tariff, error) {
+	if requestPayload.Tariff != nil && requestPayload.TariffName != "" {
+		return nil, fmt.Errorf("give either tariff or tariffName, not both")
+	}
+	if requestPayload.Tariff != nil {
+		if err := requestPayload.Tariff.Validate(); err != nil {
+			return nil, err
+		}
+		return requestPayload.Tariff, nil
+	}
+	if requestPayload.TariffName == "" {
+		return nil, nil
+	}
+	path, ok := c.TariffPaths[requestPayload.TariffName]
+	if !ok {
+		return nil, fmt.Errorf("unknown tariff %q, add it to tariffPaths in the config", requestPayload.TariffName)
+	}
+	tariff, err := LoadTariff(path)
+	if err != nil {
+		return nil, err
+	}
+	if tariff.Name == "" {
+		tariff.Name = requestPayload.TariffName
+	}
+	return tariff, nil
+}
@@ -0,0 +1,502 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/externaldata"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/utils"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	LotFIFO       = "fifo"
+	LotLIFO       = "lifo"
+	LotHIFO       = "hifo"
+	LotSpecificID = "specific-id"
+
+	// defaultLongTermDays is how long a lot has to be held for its gain to
+	// be long term, more than a year in the US.
+	defaultLongTermDays = 365
+
+	// maxLotPriceCarryDays is how many days a lot may be valued at the last
+	// price before it, to bridge a gap in the price data. Lots further from a
+	// price are an error rather than valued at a stale one.
+	maxLotPriceCarryDays = 3
+)
+
+// LotMethods returns the ways sales can be matched to lots.
+func LotMethods() []string {
+	return []string{LotFIFO, LotLIFO, LotHIFO, LotSpecificID}
+}
+
+// TaxRequest asks for the tax view of a mining operation. The mined ledger
+// is required; startDate, the costs, depreciation and the sales are read the
+// same way as for the report. Sales are matched to the mined lots by
+// LotMethod, fifo by default. A gain is long term when its lot was held more
+// than LongTermDays, 365 by default.
+type TaxRequest struct {
+	RequestPayload
+	LotMethod    string `json:"lotMethod"`
+	LongTermDays int    `json:"longTermDays"`
+}
+
+// TaxLot is the coins received on one day. Price is their fair market value
+// per BTC that day, so Income, what they were worth, is also their cost
+// basis. Remaining is what is left of them after the sales.
+type TaxLot struct {
+	ID        string  `json:"id"`
+	Bitcoin   float64 `json:"bitcoin"`
+	Price     float64 `json:"price"`
+	Income    float64 `json:"income"`
+	Remaining float64 `json:"remaining"`
+}
+
+// RealizedGain is the part of a sale that came out of one lot.
+type RealizedGain struct {
+	SaleDate  string  `json:"saleDate"`
+	LotID     string  `json:"lotId"`
+	Bitcoin   float64 `json:"bitcoin"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+	HeldDays  int64   `json:"heldDays"`
+	LongTerm  bool    `json:"longTerm"`
+}
+
+// TaxYear totals a calendar year. Income is the mined coins' value at
+// receipt; the electricity and hardware depreciation of the year are shown
+// next to it, and NetIncome takes them off.
+type TaxYear struct {
+	Year            int     `json:"year"`
+	BitcoinReceived float64 `json:"bitcoinReceived"`
+	Income          float64 `json:"income"`
+	ElectricCosts   float64 `json:"electricCosts"`
+	Depreciation    float64 `json:"depreciation"`
+	NetIncome       float64 `json:"netIncome"`
+	BitcoinSold     float64 `json:"bitcoinSold"`
+	Proceeds        float64 `json:"proceeds"`
+	CostBasis       float64 `json:"costBasis"`
+	ShortTermGains  float64 `json:"shortTermGains"`
+	LongTermGains   float64 `json:"longTermGains"`
+}
+
+// TaxReport is the lots, the realized gains and the yearly totals from
+// StartDate through EndDate, and what is still held.
+type TaxReport struct {
+	StartDate     string         `json:"startDate"`
+	EndDate       string         `json:"endDate"`
+	Currency      string         `json:"currency"`
+	PriceSource   string         `json:"priceSource"`
+	PriceField    string         `json:"priceField"`
+	LotMethod     string         `json:"lotMethod"`
+	Years         []TaxYear      `json:"years"`
+	Lots          []TaxLot       `json:"lots"`
+	Gains         []RealizedGain `json:"gains"`
+	HeldBitcoin   float64        `json:"heldBitcoin"`
+	HeldCostBasis float64        `json:"heldCostBasis"`
+}
+
+// Tax values each day's mined coins at that day's price, matches the sales
+// to them and totals income, deductions and gains by year. Prices are the
+// request's priceField, the close by default.
+func (c *Client) Tax(request TaxRequest, externalData externaldata.Interface, utils utils.Interface) (*TaxReport, error) {
+	report := &TaxReport{LotMethod: strings.ToLower(request.LotMethod), PriceField: request.PriceField}
+	if report.LotMethod == "" {
+		report.LotMethod = LotFIFO
+	}
+	if report.PriceField == "" {
+		report.PriceField = pricedata.FieldClose
+	}
+	known := false
+	for _, method := range LotMethods() {
+		known = known || report.LotMethod == method
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown lot method %q, expected one of %s", request.LotMethod, strings.Join(LotMethods(), ", "))
+	}
+	if err := pricedata.ValidPriceField(report.PriceField); err != nil {
+		return nil, err
+	}
+	longTermDays := int64(request.LongTermDays)
+	if longTermDays == 0 {
+		longTermDays = defaultLongTermDays
+	}
+
+	end := time.Now()
+	var err error
+	for _, date := range []string{request.AsOfDate, request.EndDate} {
+		if date == "" {
+			continue
+		}
+		if end, err = utils.ParseDate(date); err != nil {
+			return nil, fmt.Errorf("error parsing date %q: %w", date, err)
+		}
+	}
+	if len(request.Machines) > 0 && request.StartDate == "" {
+		if request.StartDate, err = FleetStartDate(request.Machines); err != nil {
+			return nil, fmt.Errorf("error with FleetStartDate: %w", err)
+		}
+	}
+	start, err := utils.ParseDate(request.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing startDate: %w", err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("startDate %s must be before %s", request.StartDate, end.Format("01/02/2006"))
+	}
+	report.StartDate, report.EndDate = start.Format("01/02/2006"), end.Format("01/02/2006")
+
+	currency := request.Currency
+	if currency == "" {
+		currency = c.Currency
+	}
+	if currency == "" {
+		currency = pricedata.BaseCurrency
+	}
+	report.Currency = strings.ToUpper(currency)
+	series, err := externalData.GetPriceSeries(request.PriceSource, currency)
+	if err != nil {
+		return nil, fmt.Errorf("error with GetPriceSeries: %w", err)
+	}
+	report.PriceSource = series.Name
+
+	ledger, err := request.Ledger()
+	if err != nil {
+		return nil, fmt.Errorf("error reading mined ledger: %w", err)
+	}
+	if len(ledger) == 0 {
+		return nil, fmt.Errorf("tax lots need a mined ledger")
+	}
+	report.Lots, err = taxLots(ledger, series, report.PriceField, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := request.CoinSales()
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		if t, _ := parseLedgerDate(sale.Date); pricedata.Day(t) > pricedata.Day(end) {
+			return nil, fmt.Errorf("sale on %s is after the end of the report, %s", sale.Date, report.EndDate)
+		}
+	}
+	report.Gains, err = matchLots(report.Lots, sales, report.LotMethod, longTermDays)
+	if err != nil {
+		return nil, err
+	}
+	for _, lot := range report.Lots {
+		report.HeldBitcoin += lot.Remaining
+		report.HeldCostBasis += lot.Remaining * lot.Price
+	}
+
+	electric, err := c.dailyElectricCosts(request.RequestPayload, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error with electric costs: %w", err)
+	}
+	report.Years, err = taxYears(report, electric, request.Depreciation, request.Machines, request.FixedCosts, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// taxLots makes a lot of each day's mined coins from start through end,
+// valued at that day's price, or the latest one up to maxLotPriceCarryDays
+// before it.
+func taxLots(ledger []MinedEntry, series *pricedata.PriceSeries, field string, start, end time.Time) ([]TaxLot, error) {
+	byDay := map[int64]float64{}
+	for _, entry := range ledger {
+		t, err := parseLedgerDate(entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing mined ledger date %q: %w", entry.Date, err)
+		}
+		if entry.Bitcoin < 0 {
+			return nil, fmt.Errorf("mined ledger entry on %s is negative", entry.Date)
+		}
+		day := pricedata.Day(t)
+		if day < pricedata.Day(start) {
+			return nil, fmt.Errorf("mined ledger entry on %s is before the start date %s", entry.Date, start.Format("01/02/2006"))
+		}
+		if day <= pricedata.Day(end) && entry.Bitcoin > 0 {
+			byDay[day] += entry.Bitcoin
+		}
+	}
+	days := make([]int64, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+
+	lots := make([]TaxLot, 0, len(days))
+	for _, day := range days {
+		date := dayDate(day)
+		t, _ := parseLedgerDate(date)
+		point, ok := series.AtOrBefore(t)
+		if !ok || day-pricedata.Day(point.Time()) > maxLotPriceCarryDays {
+			return nil, fmt.Errorf("no %s price on %s to value the coins mined", series.Name, date)
+		}
+		lot := TaxLot{ID: date, Bitcoin: byDay[day], Price: point.Price(field), Remaining: byDay[day]}
+		lot.Income = lot.Bitcoin * lot.Price
+		lots = append(lots, lot)
+	}
+	return lots, nil
+}
+
+// matchLots takes each sale, in date order, out of the lots received by
+// then and returns the gains. The lots' Remaining is used up as it goes.
+func matchLots(lots []TaxLot, sales []CoinSale, method string, longTermDays int64) ([]RealizedGain, error) {
+	sort.SliceStable(sales, func(i, j int) bool {
+		a, _ := parseLedgerDate(sales[i].Date)
+		b, _ := parseLedgerDate(sales[j].Date)
+		return a.Before(b)
+	})
+	lotDays := make([]int64, len(lots))
+	for i, lot := range lots {
+		t, _ := parseLedgerDate(lot.ID)
+		lotDays[i] = pricedata.Day(t)
+	}
+
+	gains := []RealizedGain{}
+	for _, sale := range sales {
+		t, err := parseLedgerDate(sale.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing sale date %q: %w", sale.Date, err)
+		}
+		if sale.Bitcoin <= 0 || sale.Proceeds < 0 {
+			return nil, fmt.Errorf("sale on %s must have positive bitcoin and proceeds", sale.Date)
+		}
+		saleDay := pricedata.Day(t)
+
+		order := []int{}
+		if method == LotSpecificID {
+			if len(sale.Lots) == 0 {
+				return nil, fmt.Errorf("sale on %s needs lots for specific-id matching", sale.Date)
+			}
+			for _, id := range sale.Lots {
+				lotTime, err := parseLedgerDate(id)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing lot %q of the sale on %s: %w", id, sale.Date, err)
+				}
+				found := -1
+				for i := range lots {
+					if lotDays[i] == pricedata.Day(lotTime) {
+						found = i
+					}
+				}
+				if found < 0 || lotDays[found] > saleDay {
+					return nil, fmt.Errorf("sale on %s names lot %s, which wasn't mined by then", sale.Date, id)
+				}
+				order = append(order, found)
+			}
+		} else {
+			for i := range lots {
+				if lotDays[i] <= saleDay {
+					order = append(order, i)
+				}
+			}
+			switch method {
+			case LotLIFO:
+				sort.SliceStable(order, func(a, b int) bool { return lotDays[order[a]] > lotDays[order[b]] })
+			case LotHIFO:
+				sort.SliceStable(order, func(a, b int) bool { return lots[order[a]].Price > lots[order[b]].Price })
+			}
+		}
+
+		left := sale.Bitcoin
+		for _, i := range order {
+			if left <= 1e-12 {
+				break
+			}
+			taken := lots[i].Remaining
+			if taken > left {
+				taken = left
+			}
+			if taken <= 0 {
+				continue
+			}
+			lots[i].Remaining -= taken
+			left -= taken
+			gain := RealizedGain{
+				SaleDate:  t.Format("01/02/2006"),
+				LotID:     lots[i].ID,
+				Bitcoin:   taken,
+				Proceeds:  sale.Proceeds * taken / sale.Bitcoin,
+				CostBasis: taken * lots[i].Price,
+				HeldDays:  saleDay - lotDays[i],
+			}
+			gain.Gain = gain.Proceeds - gain.CostBasis
+			gain.LongTerm = gain.HeldDays > longTermDays
+			gains = append(gains, gain)
+		}
+		if left > 1e-12 {
+			return nil, fmt.Errorf("sale on %s is %.8f BTC more than the lots it can come from", sale.Date, left)
+		}
+	}
+	return gains, nil
+}
+
+// taxYears totals the lots, gains, electric costs (one per day from start)
+// and, when schedule is given, the hardware's depreciation by calendar year.
+func taxYears(report *TaxReport, electric []float64, schedule *DepreciationSchedule, machines []Machine, fixedCosts float64, start, end time.Time) ([]TaxYear, error) {
+	var valuer HardwareValuer
+	if schedule != nil {
+		var err error
+		if valuer, err = schedule.Valuer(); err != nil {
+			return nil, err
+		}
+	}
+	type hardware struct {
+		cost         float64
+		bought, sold time.Time
+	}
+	held := []hardware{{cost: fixedCosts, bought: start}}
+	if valuer == nil {
+		held = nil
+	} else if len(machines) > 0 {
+		fleet, err := parseFleet(machines)
+		if err != nil {
+			return nil, err
+		}
+		held = held[:0]
+		for _, m := range fleet {
+			h := hardware{cost: m.PurchasePrice}
+			h.bought, _ = parseLedgerDate(dayDate(m.purchased))
+			if m.DecommissionDate != "" {
+				h.sold, _ = parseLedgerDate(dayDate(m.decommissioned))
+			}
+			held = append(held, h)
+		}
+	}
+
+	years := []TaxYear{}
+	index := map[int]int{}
+	year := func(t time.Time) *TaxYear {
+		if i, ok := index[t.Year()]; ok {
+			return &years[i]
+		}
+		return nil
+	}
+	for y := start.Year(); y <= end.Year(); y++ {
+		index[y] = len(years)
+		years = append(years, TaxYear{Year: y})
+
+		from := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(y+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if last := end.AddDate(0, 0, 1); to.After(last) {
+			to = last
+		}
+		for _, h := range held {
+			a, b := from, to
+			if h.bought.After(a) {
+				a = h.bought
+			}
+			if !h.sold.IsZero() && h.sold.Before(b) {
+				b = h.sold
+			}
+			if !a.Before(b) {
+				continue
+			}
+			before, err := valuer(h.cost, h.bought, a)
+			if err != nil {
+				return nil, err
+			}
+			after, err := valuer(h.cost, h.bought, b)
+			if err != nil {
+				return nil, err
+			}
+			years[len(years)-1].Depreciation += before - after
+		}
+	}
+
+	for i, cost := range electric {
+		if y := year(start.AddDate(0, 0, i)); y != nil {
+			y.ElectricCosts += cost
+		}
+	}
+	for _, lot := range report.Lots {
+		t, _ := parseLedgerDate(lot.ID)
+		if y := year(t); y != nil {
+			y.BitcoinReceived += lot.Bitcoin
+			y.Income += lot.Income
+		}
+	}
+	for _, gain := range report.Gains {
+		t, _ := parseLedgerDate(gain.SaleDate)
+		y := year(t)
+		if y == nil {
+			continue
+		}
+		y.BitcoinSold += gain.Bitcoin
+		y.Proceeds += gain.Proceeds
+		y.CostBasis += gain.CostBasis
+		if gain.LongTerm {
+			y.LongTermGains += gain.Gain
+		} else {
+			y.ShortTermGains += gain.Gain
+		}
+	}
+	for i := range years {
+		years[i].NetIncome = years[i].Income - years[i].ElectricCosts - years[i].Depreciation
+	}
+	return years, nil
+}
+
+// dailyElectricCosts returns what the electricity cost each day from start
+// through end: the fleet's days, a tariff's, electicCosts spread evenly or
+// watts at kwhPrice, less the heat reuse credit when given, as in the report.
+func (c *Client) dailyElectricCosts(requestPayload RequestPayload, start, end time.Time) ([]float64, error) {
+	tariff, err := c.requestTariff(requestPayload)
+	if err != nil {
+		return nil, err
+	}
+	var loads, costs []float64
+	if len(requestPayload.Machines) > 0 {
+		fleet, err := FleetSchedule(requestPayload.Machines, start, end, requestPayload.KwhPrice, requestPayload.UptimePercent, requestPayload.ElectricCosts)
+		if err != nil {
+			return nil, err
+		}
+		if tariff != nil {
+			if _, err := fleet.ApplyTariff(tariff, start, requestPayload.UptimePercent); err != nil {
+				return nil, err
+			}
+		}
+		for _, day := range fleet.Days {
+			loads = append(loads, day.Watts)
+			costs = append(costs, day.Electric)
+		}
+	} else {
+		days := pricedata.Day(end) - pricedata.Day(start) + 1
+		loads = make([]float64, days)
+		for i := range loads {
+			loads[i] = requestPayload.Watts
+		}
+		if tariff != nil {
+			if costs, _, err = tariff.DailyCosts(start, loads, requestPayload.UptimePercent); err != nil {
+				return nil, err
+			}
+		} else {
+			costs = make([]float64, days)
+			for i := range costs {
+				if requestPayload.ElectricCosts != nil && *requestPayload.ElectricCosts != 0 {
+					costs[i] = *requestPayload.ElectricCosts / float64(days)
+				} else {
+					costs[i] = c.ElectricCosts(requestPayload.KwhPrice, requestPayload.UptimePercent, 1, requestPayload.Watts)
+				}
+			}
+		}
+	}
+
+	if requestPayload.HeatReuse != nil {
+		credits, _, err := requestPayload.HeatReuse.Credits(start, loads, costs, requestPayload.UptimePercent)
+		if err != nil {
+			return nil, fmt.Errorf("error with heat reuse: %w", err)
+		}
+		for i := range costs {
+			costs[i] -= credits[i]
+		}
+	}
+	return costs, nil
+}
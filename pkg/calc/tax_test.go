@@ -0,0 +1,139 @@
+package calc
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"math"
+	"testing"
+)
+
+// testLots are a coin received on each of 07/01 to 07/03/2022, worth 100,
+// 300 and 200.
+func testLots() []TaxLot {
+	lots := []TaxLot{}
+	for i, price := range []float64{100, 300, 200} {
+		id := testDates(i)[0].Format("01/02/2006")
+		lots = append(lots, TaxLot{ID: id, Bitcoin: 1, Price: price, Income: price, Remaining: 1})
+	}
+	return lots
+}
+
+func TestMatchLots(t *testing.T) {
+	type taken struct {
+		lot                          string
+		bitcoin, costBasis, proceeds float64
+		heldDays                     int64
+	}
+	sale := CoinSale{Date: "07/05/2022", Bitcoin: 1.5, Proceeds: 600}
+
+	tests := []struct {
+		name    string
+		method  string
+		lots    []string
+		want    []taken
+		wantErr bool
+	}{
+		{name: "fifo", method: LotFIFO, want: []taken{
+			{"07/01/2022", 1, 100, 400, 4},
+			{"07/02/2022", 0.5, 150, 200, 3},
+		}},
+		{name: "lifo", method: LotLIFO, want: []taken{
+			{"07/03/2022", 1, 200, 400, 2},
+			{"07/02/2022", 0.5, 150, 200, 3},
+		}},
+		{name: "hifo", method: LotHIFO, want: []taken{
+			{"07/02/2022", 1, 300, 400, 3},
+			{"07/03/2022", 0.5, 100, 200, 2},
+		}},
+		{name: "specific id", method: LotSpecificID, lots: []string{"2022-07-03", "07/01/2022"}, want: []taken{
+			{"07/03/2022", 1, 200, 400, 2},
+			{"07/01/2022", 0.5, 50, 200, 4},
+		}},
+		{name: "specific id without lots", method: LotSpecificID, wantErr: true},
+		{name: "specific id naming a lot not mined", method: LotSpecificID, lots: []string{"07/04/2022"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sale
+			s.Lots = tt.lots
+			lots := testLots()
+			gains, err := matchLots(lots, []CoinSale{s}, tt.method, defaultLongTermDays)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matchLots err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(gains) != len(tt.want) {
+				t.Fatalf("got %d gains, want %d: %+v", len(gains), len(tt.want), gains)
+			}
+			remaining := 0.0
+			for i, want := range tt.want {
+				got := gains[i]
+				if got.LotID != want.lot || math.Abs(got.Bitcoin-want.bitcoin) > 1e-9 ||
+					math.Abs(got.CostBasis-want.costBasis) > 1e-9 || math.Abs(got.Proceeds-want.proceeds) > 1e-9 ||
+					got.HeldDays != want.heldDays {
+					t.Fatalf("gain %d = %+v, want %+v", i, got, want)
+				}
+				if math.Abs(got.Gain-(got.Proceeds-got.CostBasis)) > 1e-9 || got.LongTerm {
+					t.Fatalf("gain %d = %+v has the wrong gain or term", i, got)
+				}
+			}
+			for _, lot := range lots {
+				remaining += lot.Remaining
+			}
+			if math.Abs(remaining-1.5) > 1e-9 {
+				t.Fatalf("lots have %v BTC left, want 1.5", remaining)
+			}
+		})
+	}
+
+	if _, err := matchLots(testLots(), []CoinSale{{Date: "07/02/2022", Bitcoin: 2.5, Proceeds: 1}}, LotFIFO, defaultLongTermDays); err == nil {
+		t.Fatal("want an error selling more than had been mined by the sale")
+	}
+	gains, err := matchLots(testLots(), []CoinSale{sale}, LotFIFO, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gains[0].LongTerm || gains[1].LongTerm {
+		t.Fatalf("with 3 long term days, want only the 07/01 lot long term: %+v", gains)
+	}
+}
+
+func TestTaxLots(t *testing.T) {
+	dates := testDates(0, 1, 2)
+	points := make([]pricedata.PricePoint, len(dates))
+	for i, date := range dates {
+		points[i] = pricedata.PricePoint{Timestamp: date.Unix(), OpenPrice: float64(100 * (i + 1))}
+	}
+	series := pricedata.NewPriceSeries("kraken", points)
+	start, end := dates[0], testDates(30)[0]
+
+	tests := []struct {
+		name    string
+		ledger  []MinedEntry
+		prices  []float64
+		wantErr bool
+	}{
+		{name: "priced on the day", ledger: []MinedEntry{{"07/01/2022", 0.1}, {"2022-07-03", 0.2}}, prices: []float64{100, 300}},
+		{name: "carried over a short gap", ledger: []MinedEntry{{"07/06/2022", 0.1}}, prices: []float64{300}},
+		{name: "past the end of the prices", ledger: []MinedEntry{{"07/07/2022", 0.1}}, wantErr: true},
+		{name: "before the start", ledger: []MinedEntry{{"06/30/2022", 0.1}}, wantErr: true},
+		{name: "negative", ledger: []MinedEntry{{"07/02/2022", -0.1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lots, err := taxLots(tt.ledger, series, pricedata.FieldOpen, start, end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("taxLots err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(lots) != len(tt.prices) {
+				t.Fatalf("got %d lots, want %d", len(lots), len(tt.prices))
+			}
+			for i, lot := range lots {
+				if lot.Price != tt.prices[i] || math.Abs(lot.Income-lot.Bitcoin*lot.Price) > 1e-9 {
+					t.Fatalf("lot %d = %+v, want price %v", i, lot, tt.prices[i])
+				}
+			}
+		})
+	}
+}
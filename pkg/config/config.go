@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
@@ -12,18 +13,70 @@ var (
 )
 
 type Config struct {
-	Environment           string `yaml:"environment"`
-	Address               string `yaml:"address"`
-	PriceDataKrakenPath   string `yaml:"priceDataKrakenPath"`
-	MessariUrl            string `yaml:"messariUrl"`
-	BlockchainInfoUrl     string `yaml:"blockchainInfoUrl"`
-	SlushPoolUrl          string `yaml:"slushPoolUrl"`
-	PriceDataCoinbasePath string `yaml:"priceDataCoinbasePath"`
+	Environment         string `yaml:"environment"`
+	Address             string `yaml:"address"`
+	PriceDataKrakenPath string `yaml:"priceDataKrakenPath"`
+	SlushPoolUrl        string `yaml:"slushPoolUrl"`
+	// PriceProviders lists the price APIs the update command fetches daily
+	// history from, tried in order until one answers.
+	PriceProviders []PriceProviderConfig `yaml:"priceProviders"`
+	// CurrentPriceProviders names, in fallback order, the providers the
+	// server asks for the current price. Empty means all, in config order.
+	CurrentPriceProviders []string `yaml:"currentPriceProviders"`
+	PriceDataCoinbasePath string   `yaml:"priceDataCoinbasePath"`
+	// PriceDataBackend is how the price data paths are stored: json (the
+	// default), csv or sqlite. With sqlite both paths may name the same file.
+	PriceDataBackend string `yaml:"priceDataBackend"`
+	// PriceDataBackups is how many rotated copies (file.bak.1, file.bak.2,
+	// ...) to keep each time a json or csv price file is rewritten.
+	PriceDataBackups int `yaml:"priceDataBackups"`
+	// PriceDataPollInterval is how often the server checks the price files
+	// for changes and reloads them, e.g. "1m". Empty turns reloading off.
+	PriceDataPollInterval string `yaml:"priceDataPollInterval"`
 	DataPlotFileName      string `yaml:"dataPlotFileName"`
+	// Currency is what costs are entered in and reports are given in when a
+	// request doesn't say. Empty means USD.
+	Currency string `yaml:"currency"`
+	// FxRatePaths maps a currency code to its daily exchange rate file, in
+	// the price file layout with openPrice as units of currency per USD.
+	FxRatePaths map[string]string `yaml:"fxRatePaths"`
+	// FxRateUrl is the Frankfurter API the update command fetches exchange
+	// rates from. Empty means the public one, serving the ECB's rates.
+	FxRateUrl string `yaml:"fxRateUrl"`
+	// PriceSourceWeights weights each exchange in the blended price source,
+	// keyed by source name. Unlisted sources get a weight of 1. Days where
+	// every exchange reports volume are additionally weighted by volume.
+	PriceSourceWeights map[string]float64 `yaml:"priceSourceWeights"`
+	// NetworkDataPath is the daily difficulty and block subsidy file used to
+	// work out what a hashrate should have mined. Empty turns that off, and
+	// so does a missing file until the update command creates it.
+	NetworkDataPath string `yaml:"networkDataPath"`
+	// NetworkDataUrl is the mempool.space API the update command fetches
+	// network difficulty from. Empty means the public one.
+	NetworkDataUrl string `yaml:"networkDataUrl"`
+	// ProjectionDifficultyGrowthPercent and ProjectionPriceGrowthPercent
+	// are the yearly growth the breakeven projection assumes when a request
+	// doesn't give its own. ProjectionDays is how far ahead it looks.
+	ProjectionDifficultyGrowthPercent float64 `yaml:"projectionDifficultyGrowthPercent"`
+	ProjectionPriceGrowthPercent      float64 `yaml:"projectionPriceGrowthPercent"`
+	ProjectionDays                    int     `yaml:"projectionDays"`
+	// TariffPaths maps a tariff name to its YAML or JSON definition, so
+	// requests can pick an electricity tariff by name.
+	TariffPaths map[string]string `yaml:"tariffPaths"`
 }
 
-func New(filepath string) (*Config, error) {
-	fd, err := os.Open(filepath)
+// PriceProviderConfig is one price API. Market is the API's own name for the
+// BTC pair: kraken-btc-usd for messari, XBTUSD for kraken, BTC-USD for
+// coinbase, bitcoin for coingecko and the currency (USD) for blockchaininfo.
+type PriceProviderConfig struct {
+	Name    string `yaml:"name"`
+	BaseUrl string `yaml:"baseUrl"`
+	Market  string `yaml:"market"`
+	ApiKey  string `yaml:"apiKey"`
+}
+
+func New(path string) (*Config, error) {
+	fd, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("error opening the config file: %w", err)
 	}
@@ -32,6 +85,27 @@ func New(filepath string) (*Config, error) {
 	if err := yaml.NewDecoder(fd).Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("error decoding the config: %w", err)
 	}
+	cfg.resolvePaths(filepath.Dir(path))
 
 	return &cfg, nil
 }
+
+// resolvePaths makes relative data file paths relative to the config file's
+// directory, so tools run from elsewhere in the repo find the same files.
+func (c *Config) resolvePaths(dir string) {
+	for _, p := range []*string{&c.PriceDataKrakenPath, &c.PriceDataCoinbasePath, &c.NetworkDataPath} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	for currency, p := range c.FxRatePaths {
+		if p != "" && !filepath.IsAbs(p) {
+			c.FxRatePaths[currency] = filepath.Join(dir, p)
+		}
+	}
+	for name, p := range c.TariffPaths {
+		if p != "" && !filepath.IsAbs(p) {
+			c.TariffPaths[name] = filepath.Join(dir, p)
+		}
+	}
+}
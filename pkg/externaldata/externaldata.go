@@ -2,11 +2,11 @@ package externaldata
 
 import (
 	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/priceprovider"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -14,81 +14,80 @@ import (
 )
 
 type Client struct {
-	PriceDataKrakenPath string
-	MessariUrl          string
-	BlockchainInfoUrl   string
-	SlushPoolUrl        string
-	httpClient          *http.Client
+	PriceSources          *pricedata.Sources
+	PriceProviders        priceprovider.PriceProvider
+	CurrentPriceProviders priceprovider.PriceProvider
+	SlushPoolUrl          string
+	httpClient            *http.Client
 }
 
 type Interface interface {
-	MessariData(apiKey string)
+	GetPriceHistory(start, end time.Time) ([]pricedata.PricePoint, error)
 	GetBitcoinPrice() (*float64, error)
+	GetBitcoinPriceOnDate(source, currency, field string, date time.Time) (*float64, error)
+	GetFXRate(currency string, date time.Time) (float64, error)
 	GetUserMinedCoinsTotal(token string) (coins float64, err error)
-	GetPriceDataFromDateRange(start string) (priceData []float64)
+	WithPriceSources(priceSources *pricedata.Sources) Interface
+	GetPriceDataVersion() string
+	GetPriceSeries(source, currency string) (*pricedata.PriceSeries, error)
+	GetPriceDataFromDateRange(source, currency, field string, start, end time.Time) (priceData []float64, err error)
+	GetNetworkData() *pricedata.NetworkSeries
 }
 
-func New(cfg *config.Config) *Client {
+func New(cfg *config.Config, priceSources *pricedata.Sources) (*Client, error) {
+	priceProviders, err := priceprovider.Configured(cfg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up price providers: %w", err)
+	}
+	currentPriceProviders, err := priceprovider.Configured(cfg, cfg.CurrentPriceProviders)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up current price providers: %w", err)
+	}
 	return &Client{
-		PriceDataKrakenPath: cfg.PriceDataKrakenPath,
-		MessariUrl:          cfg.MessariUrl,
-		BlockchainInfoUrl:   cfg.BlockchainInfoUrl,
-		SlushPoolUrl:        cfg.SlushPoolUrl,
+		PriceSources:          priceSources,
+		PriceProviders:        priceProviders,
+		CurrentPriceProviders: currentPriceProviders,
+		SlushPoolUrl:          cfg.SlushPoolUrl,
 		httpClient: &http.Client{
 			Timeout: time.Second * 600,
 		},
-	}
+	}, nil
 }
 
-func (c *Client) MessariData(apiKey string) {
-
-	req, err := http.NewRequest("GET", c.MessariUrl, nil)
-	if err != nil {
-		fmt.Printf("Got error in request %s", err.Error())
-		return
-	}
-	req.Header.Add("x-messari-api-key", apiKey)
-	response, err := c.httpClient.Do(req)
-	if err != nil {
-		fmt.Printf("Got error in do %s", err.Error())
-		return
-	}
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Printf("Error with read: %s\n", err.Error())
-		log.Fatalln(err)
-	}
-	defer response.Body.Close()
-
-	vals := gjson.GetBytes(body, "data.values").Array()
-	fmt.Printf("num vals: %d\n", len(vals))
-	for _, v := range vals {
-		timestamp := v.Array()[0].String()[0:10]
-		openPrice := v.Array()[1].String()
-		fmt.Printf("timestamp: %s openPrice: %s\n", timestamp, openPrice)
-	}
+// GetPriceHistory fetches daily bars from the configured price providers,
+// falling back down the list when one fails.
+func (c *Client) GetPriceHistory(start, end time.Time) ([]pricedata.PricePoint, error) {
+	return c.PriceProviders.History(start, end)
 }
 
+// GetBitcoinPrice asks the current price providers in order and returns the
+// first price one of them gives.
 func (c *Client) GetBitcoinPrice() (*float64, error) {
-	req, err := http.NewRequest("GET", c.BlockchainInfoUrl, nil)
+	price, err := c.CurrentPriceProviders.Current()
 	if err != nil {
-		return nil, fmt.Errorf("Got error %w", err)
-	}
-	response, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("Got error %w", err)
+		return nil, err
 	}
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		log.Fatalln(err)
+	return &price, nil
+}
+
+// GetBitcoinPriceOnDate returns the source's field price on date in currency
+// from the loaded price data, for valuing coins as of a past day. An empty
+// field means the open, as for the history.
+func (c *Client) GetBitcoinPriceOnDate(source, currency, field string, date time.Time) (*float64, error) {
+	if err := pricedata.ValidPriceField(field); err != nil {
+		return nil, err
 	}
-	defer response.Body.Close()
-	s, err := strconv.ParseFloat(string(body), 64)
+	series, err := c.GetPriceSeries(source, currency)
 	if err != nil {
 		return nil, err
 	}
-	priceVal := 500 / s
-	return &priceVal, nil
+	point, ok := series.At(date)
+	if !ok {
+		last, _ := series.Last()
+		return nil, fmt.Errorf("no %s price on %s, data ends %s", series.Name, date.Format("01/02/2006"), last.Time().Format("01/02/2006"))
+	}
+	price := point.Price(field)
+	return &price, nil
 }
 
 func (c *Client) GetUserMinedCoinsTotal(token string) (coins float64, err error) {
@@ -122,22 +121,54 @@ func (c *Client) GetUserMinedCoinsTotal(token string) (coins float64, err error)
 	return coins, err
 }
 
-func (c *Client) GetPriceDataFromDateRange(start string) (priceData []float64) {
-	content, err := os.ReadFile(c.PriceDataKrakenPath)
+// WithPriceSources returns a copy of the client that reads prices from
+// priceSources, so a request keeps one consistent snapshot even if the
+// server reloads its price data partway through.
+func (c *Client) WithPriceSources(priceSources *pricedata.Sources) Interface {
+	snapshot := *c
+	snapshot.PriceSources = priceSources
+	return &snapshot
+}
+
+func (c *Client) GetPriceDataVersion() string {
+	return c.PriceSources.Version
+}
+
+// GetFXRate returns how many units of currency one USD bought on date.
+func (c *Client) GetFXRate(currency string, date time.Time) (float64, error) {
+	return c.PriceSources.FX.Rate(currency, date)
+}
+
+// GetPriceSeries returns the source's prices converted to currency at each
+// day's exchange rate. An empty currency means USD.
+func (c *Client) GetPriceSeries(source, currency string) (*pricedata.PriceSeries, error) {
+	series, err := c.PriceSources.Get(source)
 	if err != nil {
-		fmt.Printf("Error reading %s: %s\n", c.PriceDataKrakenPath, err.Error())
+		return nil, err
 	}
-	vals := gjson.GetBytes(content, "data").Array()
-	foundStart := false
-	for _, v := range vals {
-		timestamp := v.Get("timestamp").String()
-		price := v.Get("openPrice").Float()
-		if timestamp == start {
-			foundStart = true
-		}
-		if foundStart {
-			priceData = append(priceData, price)
-		}
+	return c.PriceSources.FX.Convert(series, currency)
+}
+
+func (c *Client) GetPriceDataFromDateRange(source, currency, field string, start, end time.Time) (priceData []float64, err error) {
+	if err := pricedata.ValidPriceField(field); err != nil {
+		return nil, err
 	}
-	return priceData
+	series, err := c.GetPriceSeries(source, currency)
+	if err != nil {
+		return nil, err
+	}
+	priceData = series.FieldPrices(field, start, end)
+	if len(priceData) == 0 {
+		first, _ := series.First()
+		last, _ := series.Last()
+		return nil, fmt.Errorf("no %s price data between %s and %s, data covers %s to %s",
+			series.Name, start.Format("01/02/2006"), end.Format("01/02/2006"),
+			first.Time().Format("01/02/2006"), last.Time().Format("01/02/2006"))
+	}
+	return priceData, nil
+}
+
+// GetNetworkData returns the loaded difficulty and subsidy history.
+func (c *Client) GetNetworkData() *pricedata.NetworkSeries {
+	return c.PriceSources.Network
 }
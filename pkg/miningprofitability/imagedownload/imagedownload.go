@@ -51,18 +51,22 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.RequestPayload) {
 
-	if requestPayload.SlushToken == nil && requestPayload.BitcoinMined == 0 {
-		h.actx.Logger.Error("error must send either slush api token or bitcoinMined")
+	if requestPayload.SlushToken == nil && requestPayload.BitcoinMined == 0 && len(requestPayload.MinedLedger) == 0 && requestPayload.MinedLedgerCsv == "" {
+		h.actx.Logger.Error("error must send either slush api token, bitcoinMined or a mined ledger")
 		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte("error must send either slush api token or bitcoinMined"))
+		_, _ = w.Write([]byte("error must send either slush api token, bitcoinMined or a mined ledger"))
 
+		return
 	}
 
-	fileName, err := h.actx.Calc.GenerateImage(*requestPayload, h.actx.ExternalData, h.actx.Utils)
+	externalData := h.actx.Snapshot()
+	fileName, err := h.actx.Calc.GenerateImage(*requestPayload, externalData, h.actx.Utils)
 	if err != nil {
 		h.actx.Logger.WithError(err).Error("error must send either slush api token or bitcoinMined")
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
+
+		return
 	}
 	fn := filepath.Base(*fileName)
 	file, err := os.OpenFile(*fileName, os.O_RDWR, 0644)
@@ -70,6 +74,13 @@ func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.Requ
 		h.actx.Logger.WithError(err).Error("error reading generated file")
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
+
+		return
+	}
+	w.Header().Set("X-Price-Data-Version", externalData.GetPriceDataVersion())
+	if priceSeries, err := externalData.GetPriceSeries(requestPayload.PriceSource, ""); err == nil {
+		last, _ := priceSeries.Last()
+		w.Header().Set("X-Price-Data-Last-Date", last.Time().Format("01/02/2006"))
 	}
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fn))
@@ -0,0 +1,123 @@
+package sensitivity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"Mining-Profitability/pkg/appcontext"
+	"Mining-Profitability/pkg/calc"
+)
+
+type Handler struct {
+	actx *appcontext.AppContext
+}
+
+func NewSensitivityHandler(actx *appcontext.AppContext) *Handler {
+	return &Handler{actx}
+}
+
+// ServeHTTP answers with the grid as JSON, or as CSV or a heatmap image when
+// the format query parameter is csv or png.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.actx.Logger.Debug("endpoint only accepts POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	a, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.actx.Logger.WithError(err).Error("error reading the request body")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error reading body"))
+
+		return
+	}
+
+	var requestPayload calc.SensitivityRequest
+	if err := json.Unmarshal(a, &requestPayload); err != nil {
+		h.actx.Logger.WithError(err).Error("error parsing the request body into sensitivityrequest struct")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error unmarshaling body"))
+
+		return
+	}
+
+	h.handleRequest(w, &requestPayload, r.URL.Query().Get("format"))
+}
+
+func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.SensitivityRequest, format string) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if requestPayload.SlushToken == nil && requestPayload.BitcoinMined == 0 && len(requestPayload.MinedLedger) == 0 && requestPayload.MinedLedgerCsv == "" {
+		h.actx.Logger.Error("error must send either slush api token, bitcoinMined or a mined ledger")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error must send either slush api token, bitcoinMined or a mined ledger"))
+
+		return
+	}
+	if format != "" && format != "json" && format != "csv" && format != "png" {
+		h.actx.Logger.Errorf("unknown sensitivity format %q", format)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error format must be json, csv or png"))
+
+		return
+	}
+
+	externalData := h.actx.Snapshot()
+	grid, err := h.actx.Calc.Sensitivity(*requestPayload, externalData, h.actx.Utils)
+	if err != nil {
+		h.actx.Logger.WithError(err).Error("error generating sensitivity grid")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+
+		return
+	}
+	w.Header().Set("X-Price-Data-Version", externalData.GetPriceDataVersion())
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusOK)
+		if err := grid.WriteCSV(w); err != nil {
+			h.actx.Logger.WithError(err).Error("error writing sensitivity csv")
+		}
+	case "png":
+		fileName, err := h.actx.Calc.MakeHeatmap(grid)
+		if err != nil {
+			h.actx.Logger.WithError(err).Error("error making sensitivity heatmap")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+
+			return
+		}
+		file, err := os.Open(*fileName)
+		if err != nil {
+			h.actx.Logger.WithError(err).Error("error reading generated file")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(*fileName)))
+		io.Copy(w, file)
+	default:
+		byteRes, err := json.Marshal(grid)
+		if err != nil {
+			h.actx.Logger.WithError(err).Error("error encoding sensitivity grid")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(byteRes)
+	}
+}
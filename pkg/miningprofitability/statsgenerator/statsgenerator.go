@@ -48,23 +48,42 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.RequestPayload) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	if requestPayload.SlushToken == nil && requestPayload.BitcoinMined == 0 {
-		h.actx.Logger.Error("error must send either slush api token or bitcoinMined")
+	if requestPayload.SlushToken == nil && requestPayload.BitcoinMined == 0 && len(requestPayload.MinedLedger) == 0 && requestPayload.MinedLedgerCsv == "" {
+		h.actx.Logger.Error("error must send either slush api token, bitcoinMined or a mined ledger")
 		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte("error must send either slush api token or bitcoinMined"))
+		_, _ = w.Write([]byte("error must send either slush api token, bitcoinMined or a mined ledger"))
 
+		return
 	}
 
-	stats, err := h.actx.Calc.GenerateStats(*requestPayload, h.actx.ExternalData, h.actx.Utils)
+	externalData := h.actx.Snapshot()
+	stats, err := h.actx.Calc.GenerateStats(*requestPayload, externalData, h.actx.Utils)
 	if err != nil {
 		h.actx.Logger.WithError(err).Error("error must send either slush api token or bitcoinMined")
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
+
+		return
 	}
 	if !requestPayload.ShowStrategyData {
-		stats.AhData = make([]float64, 0)
-		stats.AntiHomeMinerData = make([]float64, 0)
-		stats.DcaData = make([]float64, 0)
+		for i := range stats.Strategies {
+			stats.Strategies[i].Data = nil
+		}
+		stats.MinedData = make([]float64, 0)
+		stats.RankingsByDay = nil
+		stats.ExpectedData = nil
+		if stats.Projection != nil {
+			stats.Projection.Days = nil
+		}
+		if stats.Simulation != nil {
+			stats.Simulation.Fan = calc.FanChart{}
+		}
+		if stats.Fleet != nil {
+			stats.Fleet.Days = nil
+		}
+		if stats.Sales != nil {
+			stats.Sales.HeldData, stats.Sales.CashData = nil, nil
+		}
 	}
 
 	byteRes, err := json.Marshal(stats)
@@ -72,6 +91,8 @@ func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.Requ
 		h.actx.Logger.WithError(err).Error("error must send either slush api token or bitcoinMined")
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
+
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
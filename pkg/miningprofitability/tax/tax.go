@@ -0,0 +1,83 @@
+package tax
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"Mining-Profitability/pkg/appcontext"
+	"Mining-Profitability/pkg/calc"
+)
+
+type Handler struct {
+	actx *appcontext.AppContext
+}
+
+func NewTaxHandler(actx *appcontext.AppContext) *Handler {
+	return &Handler{actx}
+}
+
+// ServeHTTP answers with the tax lots, realized gains and yearly totals as
+// JSON.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.actx.Logger.Debug("endpoint only accepts POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	a, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.actx.Logger.WithError(err).Error("error reading the request body")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error reading body"))
+
+		return
+	}
+
+	var requestPayload calc.TaxRequest
+	if err := json.Unmarshal(a, &requestPayload); err != nil {
+		h.actx.Logger.WithError(err).Error("error parsing the request body into taxrequest struct")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error unmarshaling body"))
+
+		return
+	}
+
+	h.handleRequest(w, &requestPayload)
+}
+
+func (h *Handler) handleRequest(w http.ResponseWriter, requestPayload *calc.TaxRequest) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if len(requestPayload.MinedLedger) == 0 && requestPayload.MinedLedgerCsv == "" {
+		h.actx.Logger.Error("error must send a mined ledger")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("error must send a mined ledger"))
+
+		return
+	}
+
+	externalData := h.actx.Snapshot()
+	report, err := h.actx.Calc.Tax(*requestPayload, externalData, h.actx.Utils)
+	if err != nil {
+		h.actx.Logger.WithError(err).Error("error generating tax report")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+
+		return
+	}
+	w.Header().Set("X-Price-Data-Version", externalData.GetPriceDataVersion())
+
+	byteRes, err := json.Marshal(report)
+	if err != nil {
+		h.actx.Logger.WithError(err).Error("error encoding tax report")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(byteRes)
+}
@@ -0,0 +1,167 @@
+package pricedata
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileLock is an advisory lock on a price file, held on a sibling ".lock"
+// file so it survives the data file being replaced by rename. Writers take it
+// exclusively, readers shared, so the server never loads a file mid-update.
+type FileLock struct {
+	file *os.File
+}
+
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// Lock blocks until it holds the lock for path.
+func Lock(path string, exclusive bool) (*FileLock, error) {
+	f, err := os.OpenFile(lockPath(path), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock for %s: %w", path, err)
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %s: %w", path, err)
+	}
+	return &FileLock{file: f}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	defer l.file.Close()
+	return unlockFile(l.file)
+}
+
+// ReadFile reads path while holding a shared lock on it.
+func ReadFile(path string) ([]byte, error) {
+	lock, err := Lock(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	return os.ReadFile(path)
+}
+
+// UpdateFile replaces the contents of path with whatever update returns for
+// its current contents. The exclusive lock is held from the read through the
+// write, so concurrent updaters can't lose each other's changes.
+func UpdateFile(path string, backups int, update func(content []byte) ([]byte, error)) error {
+	lock, err := Lock(path, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	updated, err := update(content)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, updated, backups)
+}
+
+// WriteFile replaces the contents of path under an exclusive lock.
+func WriteFile(path string, content []byte, backups int) error {
+	return UpdateFile(path, backups, func([]byte) ([]byte, error) {
+		return content, nil
+	})
+}
+
+// writeFileAtomic writes content to a temp file in the same directory, fsyncs
+// it and renames it over path, so readers see either the old file or the new
+// one and never a partial write. The caller must hold the exclusive lock.
+func writeFileAtomic(path string, content []byte, backups int) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temp file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing temp file for %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing temp file for %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temp file for %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("error setting permissions on temp file for %s: %w", path, err)
+	}
+
+	if err := rotateBackups(path, backups); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", path, err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// rotateBackups shifts path.bak.1 .. path.bak.(n-1) up by one and copies the
+// current file to path.bak.1, dropping the oldest.
+func rotateBackups(path string, backups int) error {
+	if backups <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	for n := backups - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error rotating backups of %s: %w", path, err)
+		}
+	}
+	if err := copyFile(path, backupPath(path, 1)); err != nil {
+		return fmt.Errorf("error backing up %s: %w", path, err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir flushes the directory entry for a rename. Not every platform
+// supports it, and the rename has already happened, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -0,0 +1,186 @@
+package pricedata
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	IssueGap          = "gap"
+	IssueDuplicate    = "duplicate"
+	IssueOutOfOrder   = "out-of-order"
+	IssueNotMidnight  = "not-midnight-utc"
+	IssueOutlier      = "outlier"
+	IssueMissingPrice = "missing-price"
+
+	ChangeFilledFromReference = "filled-from-reference"
+	ChangeInterpolated        = "interpolated"
+	ChangeRemovedDuplicate    = "removed-duplicate"
+	ChangeMovedToMidnight     = "moved-to-midnight-utc"
+	ChangeReordered           = "reordered"
+	ChangeRemovedMissingPrice = "removed-missing-price"
+)
+
+type Issue struct {
+	Kind      string `json:"kind"`
+	Timestamp int64  `json:"timestamp"`
+	Detail    string `json:"detail"`
+}
+
+type AuditReport struct {
+	Source string  `json:"source"`
+	Points int     `json:"points"`
+	First  int64   `json:"first"`
+	Last   int64   `json:"last"`
+	Issues []Issue `json:"issues"`
+}
+
+// Count returns how many issues of the given kind the report holds.
+func (r AuditReport) Count(kind string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+type Change struct {
+	Action    string  `json:"action"`
+	Timestamp int64   `json:"timestamp"`
+	OldPrice  float64 `json:"oldPrice,omitempty"`
+	NewPrice  float64 `json:"newPrice,omitempty"`
+	Detail    string  `json:"detail"`
+}
+
+func formatDay(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+}
+
+// Audit checks points, in file order, for gaps, duplicate days, out-of-order
+// entries, timestamps that aren't midnight UTC, missing prices and daily
+// moves larger than outlierPercent.
+func Audit(source string, points []PricePoint, outlierPercent float64) AuditReport {
+	report := AuditReport{Source: source, Points: len(points), Issues: []Issue{}}
+	if len(points) == 0 {
+		return report
+	}
+
+	seen := map[int64]int64{}
+	for i, p := range points {
+		if p.Timestamp%secondsPerDay != 0 {
+			report.Issues = append(report.Issues, Issue{IssueNotMidnight, p.Timestamp,
+				fmt.Sprintf("%s is %s past midnight UTC", formatDay(p.Timestamp), time.Duration(p.Timestamp-dayOf(p.Timestamp)*secondsPerDay)*time.Second)})
+		}
+		if p.OpenPrice <= 0 {
+			report.Issues = append(report.Issues, Issue{IssueMissingPrice, p.Timestamp,
+				fmt.Sprintf("%s has open price %v", formatDay(p.Timestamp), p.OpenPrice)})
+		}
+		if i > 0 && p.Timestamp < points[i-1].Timestamp {
+			report.Issues = append(report.Issues, Issue{IssueOutOfOrder, p.Timestamp,
+				fmt.Sprintf("%s appears after %s", formatDay(p.Timestamp), formatDay(points[i-1].Timestamp))})
+		}
+		day := dayOf(p.Timestamp)
+		if first, ok := seen[day]; ok {
+			report.Issues = append(report.Issues, Issue{IssueDuplicate, p.Timestamp,
+				fmt.Sprintf("%s already present at timestamp %d", formatDay(p.Timestamp), first)})
+			continue
+		}
+		seen[day] = p.Timestamp
+	}
+
+	series := NewPriceSeries(source, points).points
+	report.First = series[0].Timestamp
+	report.Last = series[len(series)-1].Timestamp
+	for i := 1; i < len(series); i++ {
+		prev, cur := series[i-1], series[i]
+		if missing := dayOf(cur.Timestamp) - dayOf(prev.Timestamp) - 1; missing > 0 {
+			report.Issues = append(report.Issues, Issue{IssueGap, (dayOf(prev.Timestamp) + 1) * secondsPerDay,
+				fmt.Sprintf("%d day(s) missing between %s and %s", missing, formatDay(prev.Timestamp), formatDay(cur.Timestamp))})
+		}
+		if prev.OpenPrice > 0 && cur.OpenPrice > 0 {
+			move := (cur.OpenPrice/prev.OpenPrice - 1) * 100
+			if math.Abs(move) > outlierPercent {
+				report.Issues = append(report.Issues, Issue{IssueOutlier, cur.Timestamp,
+					fmt.Sprintf("%s moved %.2f%% from %v to %v", formatDay(cur.Timestamp), move, prev.OpenPrice, cur.OpenPrice)})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Timestamp < report.Issues[j].Timestamp
+	})
+	return report
+}
+
+// Repair returns points sorted one per day at midnight UTC with gaps filled,
+// along with every change it made. Missing days are taken from reference when
+// it has them and linearly interpolated from the open prices otherwise.
+// Outliers are left alone since a large daily move is usually real.
+func Repair(points []PricePoint, reference *PriceSeries) ([]PricePoint, []Change) {
+	changes := []Change{}
+
+	cleaned := make([]PricePoint, 0, len(points))
+	seen := map[int64]bool{}
+	for i, p := range points {
+		if p.OpenPrice <= 0 {
+			changes = append(changes, Change{ChangeRemovedMissingPrice, p.Timestamp, p.OpenPrice, 0,
+				fmt.Sprintf("dropped %s with open price %v", formatDay(p.Timestamp), p.OpenPrice)})
+			continue
+		}
+		day := dayOf(p.Timestamp)
+		if seen[day] {
+			changes = append(changes, Change{ChangeRemovedDuplicate, p.Timestamp, p.OpenPrice, 0,
+				fmt.Sprintf("dropped duplicate entry for %s", formatDay(p.Timestamp))})
+			continue
+		}
+		seen[day] = true
+		if i > 0 && p.Timestamp < points[i-1].Timestamp {
+			changes = append(changes, Change{ChangeReordered, p.Timestamp, p.OpenPrice, p.OpenPrice,
+				fmt.Sprintf("moved %s into date order", formatDay(p.Timestamp))})
+		}
+		if midnight := day * secondsPerDay; midnight != p.Timestamp {
+			changes = append(changes, Change{ChangeMovedToMidnight, midnight, p.OpenPrice, p.OpenPrice,
+				fmt.Sprintf("timestamp %d moved to %d", p.Timestamp, midnight)})
+			p.Timestamp = midnight
+		}
+		cleaned = append(cleaned, p)
+	}
+	sort.SliceStable(cleaned, func(i, j int) bool { return cleaned[i].Timestamp < cleaned[j].Timestamp })
+
+	repaired := make([]PricePoint, 0, len(cleaned))
+	for i, p := range cleaned {
+		if i > 0 {
+			prev := cleaned[i-1]
+			gap := dayOf(p.Timestamp) - dayOf(prev.Timestamp)
+			for d := int64(1); d < gap; d++ {
+				timestamp := (dayOf(prev.Timestamp) + d) * secondsPerDay
+				if reference != nil {
+					if ref, ok := reference.At(time.Unix(timestamp, 0)); ok && ref.OpenPrice > 0 {
+						filled := PricePoint{Timestamp: timestamp, OpenPrice: ref.OpenPrice}
+						// only carry the full bar over when the file being repaired records one
+						if prev.ClosePrice != 0 {
+							filled = ref
+							filled.Timestamp = timestamp
+						}
+						repaired = append(repaired, filled)
+						changes = append(changes, Change{ChangeFilledFromReference, timestamp, 0, ref.OpenPrice,
+							fmt.Sprintf("filled %s from %s", formatDay(timestamp), reference.Name)})
+						continue
+					}
+				}
+				price := prev.OpenPrice + (p.OpenPrice-prev.OpenPrice)*float64(d)/float64(gap)
+				repaired = append(repaired, PricePoint{Timestamp: timestamp, OpenPrice: price})
+				changes = append(changes, Change{ChangeInterpolated, timestamp, 0, price,
+					fmt.Sprintf("interpolated %s between %s and %s", formatDay(timestamp), formatDay(prev.Timestamp), formatDay(p.Timestamp))})
+			}
+		}
+		repaired = append(repaired, p)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Timestamp < changes[j].Timestamp })
+	return repaired, changes
+}
@@ -0,0 +1,224 @@
+package pricedata
+
+import (
+	"Mining-Profitability/pkg/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ConfiguredSources returns the names of the sources config.yaml gives a
+// path for, in the order they are loaded. The first one is the default.
+func ConfiguredSources(cfg *config.Config) []string {
+	sources := []string{}
+	for _, source := range []string{SourceKraken, SourceCoinbase} {
+		if ConfiguredPath(cfg, source) != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// ConfiguredPath returns the path config.yaml gives for source.
+func ConfiguredPath(cfg *config.Config, source string) string {
+	switch source {
+	case SourceKraken:
+		return cfg.PriceDataKrakenPath
+	case SourceCoinbase:
+		return cfg.PriceDataCoinbasePath
+	}
+	return ""
+}
+
+// ConfiguredStore returns the store config.yaml sets up for source.
+func ConfiguredStore(cfg *config.Config, source string) (PriceStore, error) {
+	path := ConfiguredPath(cfg, source)
+	if path == "" {
+		return nil, fmt.Errorf("no price data path configured for %q", source)
+	}
+	return NewStore(cfg.PriceDataBackend, path, source, cfg.PriceDataBackups)
+}
+
+// ConfiguredCurrencies returns the currencies config.yaml gives an exchange
+// rate file for, sorted.
+func ConfiguredCurrencies(cfg *config.Config) []string {
+	currencies := []string{}
+	for currency := range cfg.FxRatePaths {
+		currencies = append(currencies, strings.ToUpper(currency))
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// ConfiguredFXPath returns the path config.yaml gives for currency's
+// exchange rates.
+func ConfiguredFXPath(cfg *config.Config, currency string) string {
+	for c, path := range cfg.FxRatePaths {
+		if strings.EqualFold(c, currency) {
+			return path
+		}
+	}
+	return ""
+}
+
+// ConfiguredFXStore returns the store holding currency's exchange rates. In a
+// shared sqlite file they are kept under the source name fx-<currency>.
+func ConfiguredFXStore(cfg *config.Config, currency string) (PriceStore, error) {
+	path := ConfiguredFXPath(cfg, currency)
+	if path == "" {
+		return nil, fmt.Errorf("no exchange rate path configured for %q", currency)
+	}
+	return NewStore(cfg.PriceDataBackend, path, "fx-"+strings.ToLower(currency), cfg.PriceDataBackups)
+}
+
+// ConfiguredPaths returns every distinct file the configured sources and
+// exchange rates live in, plus the network data file once it exists.
+func ConfiguredPaths(cfg *config.Config) []string {
+	all := []string{}
+	for _, source := range ConfiguredSources(cfg) {
+		all = append(all, ConfiguredPath(cfg, source))
+	}
+	for _, currency := range ConfiguredCurrencies(cfg) {
+		all = append(all, ConfiguredFXPath(cfg, currency))
+	}
+	if cfg.NetworkDataPath != "" {
+		if _, err := os.Stat(cfg.NetworkDataPath); err == nil {
+			all = append(all, cfg.NetworkDataPath)
+		}
+	}
+
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, path := range all {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Checksum returns a short hash of the contents of paths, used as the
+// version of the price data loaded from them.
+func Checksum(paths []string) (string, error) {
+	contents, err := readFiles(paths)
+	if err != nil {
+		return "", err
+	}
+	return checksum(paths, contents), nil
+}
+
+func checksum(paths []string, contents map[string][]byte) string {
+	h := sha256.New()
+	for _, path := range paths {
+		h.Write(contents[path])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// readFiles reads every one of paths while holding shared locks on all of
+// them, so the contents are one consistent snapshot even when an update is
+// writing some of them.
+func readFiles(paths []string) (map[string][]byte, error) {
+	locks := make([]*FileLock, 0, len(paths))
+	defer func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}()
+	for _, path := range paths {
+		lock, err := Lock(path, false)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	contents := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		contents[path] = content
+	}
+	return contents, nil
+}
+
+// fileDecoder is a store kept in a single file, which can parse contents
+// already read from it.
+type fileDecoder interface {
+	decode(content []byte) ([]PricePoint, error)
+}
+
+// loadContent is Load, parsing content, what was read from path, when store
+// is a file store. A sqlite store is read through its own transaction.
+func loadContent(name string, store PriceStore, path string, content []byte) (*PriceSeries, error) {
+	decoder, ok := store.(fileDecoder)
+	if !ok {
+		return Load(name, store)
+	}
+	points, err := decoder.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("error loading %s: no price data", path)
+	}
+	return NewPriceSeries(name, points), nil
+}
+
+// LoadSources loads every configured source and builds the median and
+// blended series from them. The files are read once, together under shared
+// locks, and the version is the hash of the same bytes that are parsed, so
+// an update landing partway through shows up as a new version next time.
+func LoadSources(cfg *config.Config) (*Sources, error) {
+	paths := ConfiguredPaths(cfg)
+	contents, err := readFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	version := checksum(paths, contents)
+
+	series := []*PriceSeries{}
+	for _, source := range ConfiguredSources(cfg) {
+		store, err := ConfiguredStore(cfg, source)
+		if err != nil {
+			return nil, err
+		}
+		path := ConfiguredPath(cfg, source)
+		ps, err := loadContent(source, store, path, contents[path])
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s price data: %w", source, err)
+		}
+		series = append(series, ps)
+	}
+	sources, err := NewSources(cfg.PriceSourceWeights, series...)
+	if err != nil {
+		return nil, err
+	}
+	fx := []*PriceSeries{}
+	for _, currency := range ConfiguredCurrencies(cfg) {
+		store, err := ConfiguredFXStore(cfg, currency)
+		if err != nil {
+			return nil, err
+		}
+		path := ConfiguredFXPath(cfg, currency)
+		ps, err := loadContent(currency, store, path, contents[path])
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s exchange rates: %w", currency, err)
+		}
+		fx = append(fx, ps)
+	}
+	sources.FX = NewFXRates(fx...)
+	if _, ok := contents[cfg.NetworkDataPath]; ok && cfg.NetworkDataPath != "" {
+		sources.Network, err = parseNetwork(cfg.NetworkDataPath, contents[cfg.NetworkDataPath])
+		if err != nil {
+			return nil, err
+		}
+	}
+	sources.Version = version
+	return sources, nil
+}
@@ -0,0 +1,132 @@
+package pricedata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+)
+
+var csvHeader = []string{"timestamp", "openPrice", "highPrice", "lowPrice", "closePrice", "volume", "vwap"}
+
+// CSVStore keeps prices in a plain CSV file with a header row. Columns are
+// matched by name, so files holding only timestamp and openPrice work too.
+type CSVStore struct {
+	Path    string
+	Backups int
+}
+
+func (s *CSVStore) String() string {
+	return s.Path
+}
+
+func (s *CSVStore) Read() ([]PricePoint, error) {
+	content, err := ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s.Path, err)
+	}
+	return s.decode(content)
+}
+
+func (s *CSVStore) Write(points []PricePoint) error {
+	return s.Update(func([]PricePoint) ([]PricePoint, error) {
+		return points, nil
+	})
+}
+
+func (s *CSVStore) Update(update func(points []PricePoint) ([]PricePoint, error)) error {
+	return UpdateFile(s.Path, s.Backups, func(content []byte) ([]byte, error) {
+		points, err := s.decode(content)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(points)
+		if err != nil {
+			return nil, err
+		}
+		return s.encode(updated)
+	})
+}
+
+func (s *CSVStore) decode(content []byte) ([]PricePoint, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", s.Path, err)
+	}
+	if len(records) == 0 {
+		return []PricePoint{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	if _, ok := columns["timestamp"]; !ok {
+		return nil, fmt.Errorf("error parsing %s: missing timestamp column", s.Path)
+	}
+
+	points := make([]PricePoint, 0, len(records)-1)
+	for line, record := range records[1:] {
+		value := func(name string) (float64, error) {
+			i, ok := columns[name]
+			if !ok || record[i] == "" {
+				return 0, nil
+			}
+			return strconv.ParseFloat(record[i], 64)
+		}
+
+		timestamp, err := strconv.ParseInt(record[columns["timestamp"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s line %d: %w", s.Path, line+2, err)
+		}
+		p := PricePoint{Timestamp: timestamp}
+		for name, field := range map[string]*float64{
+			"openPrice":  &p.OpenPrice,
+			"highPrice":  &p.HighPrice,
+			"lowPrice":   &p.LowPrice,
+			"closePrice": &p.ClosePrice,
+			"volume":     &p.Volume,
+			"vwap":       &p.Vwap,
+		} {
+			if *field, err = value(name); err != nil {
+				return nil, fmt.Errorf("error parsing %s line %d %s: %w", s.Path, line+2, name, err)
+			}
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func (s *CSVStore) encode(points []PricePoint) ([]byte, error) {
+	format := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("error encoding price data: %w", err)
+	}
+	for _, p := range points {
+		record := []string{
+			strconv.FormatInt(p.Timestamp, 10),
+			strconv.FormatFloat(p.OpenPrice, 'f', -1, 64),
+			format(p.HighPrice),
+			format(p.LowPrice),
+			format(p.ClosePrice),
+			format(p.Volume),
+			format(p.Vwap),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("error encoding price data: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("error encoding price data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package pricedata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BaseCurrency is the currency the price files are quoted in.
+	BaseCurrency = "USD"
+
+	// maxFXCarryDays is how long the last rate is used past the end of a rate
+	// file, enough to cover the weekends and holidays reference rates skip.
+	maxFXCarryDays = 7
+)
+
+// FXRates holds a daily rate series per currency, each day's open being how
+// many units of that currency one USD bought. Converted series are kept, so
+// each price series is converted to a currency once per load.
+type FXRates struct {
+	series map[string]*PriceSeries
+
+	mu        sync.Mutex
+	converted map[fxKey]*PriceSeries
+}
+
+type fxKey struct {
+	series   *PriceSeries
+	currency string
+}
+
+// NewFXRates indexes series by their names, which are currency codes.
+func NewFXRates(series ...*PriceSeries) *FXRates {
+	r := &FXRates{series: make(map[string]*PriceSeries, len(series)), converted: map[fxKey]*PriceSeries{}}
+	for _, ps := range series {
+		r.series[strings.ToUpper(ps.Name)] = ps
+	}
+	return r
+}
+
+// Currencies returns USD plus every currency with loaded rates, sorted.
+func (r *FXRates) Currencies() []string {
+	currencies := []string{BaseCurrency}
+	for currency := range r.series {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies[1:])
+	return currencies
+}
+
+// Rate returns how many units of currency one USD bought on date. A day
+// without a rate, such as a weekend, uses the latest one before it. Dates more
+// than maxFXCarryDays past the last rate are an error rather than priced at a
+// stale rate.
+func (r *FXRates) Rate(currency string, date time.Time) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == BaseCurrency {
+		return 1, nil
+	}
+	ps, ok := r.series[currency]
+	if !ok {
+		return 0, r.unknownCurrency(currency)
+	}
+	point, ok := ps.AtOrBefore(date)
+	if !ok || point.OpenPrice <= 0 {
+		first, _ := ps.First()
+		return 0, fmt.Errorf("no %s exchange rate on %s, rates start %s", currency, date.Format("01/02/2006"), first.Time().Format("01/02/2006"))
+	}
+	if last, _ := ps.Last(); Day(date)-Day(last.Time()) > maxFXCarryDays {
+		return 0, fmt.Errorf("no %s exchange rate on %s, rates end %s", currency, date.Format("01/02/2006"), last.Time().Format("01/02/2006"))
+	}
+	return point.OpenPrice, nil
+}
+
+// Convert returns series with every price converted to currency at the rate
+// of its own day. Volume stays in BTC. Only the days the rates cover are
+// kept: days before the first rate, and past the end of the rates, are left
+// out rather than failing the whole series or using a stale rate.
+func (r *FXRates) Convert(series *PriceSeries, currency string) (*PriceSeries, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == BaseCurrency {
+		return series, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if converted, ok := r.converted[fxKey{series, currency}]; ok {
+		return converted, nil
+	}
+	points := make([]PricePoint, 0, series.Len())
+	ps, ok := r.series[currency]
+	if !ok {
+		return nil, r.unknownCurrency(currency)
+	}
+	first, _ := ps.First()
+	last, _ := ps.Last()
+	for _, p := range series.points {
+		if Day(p.Time()) < Day(first.Time()) {
+			continue
+		}
+		if Day(p.Time())-Day(last.Time()) > maxFXCarryDays {
+			break
+		}
+		rate, err := r.Rate(currency, p.Time())
+		if err != nil {
+			return nil, err
+		}
+		p.OpenPrice *= rate
+		p.HighPrice *= rate
+		p.LowPrice *= rate
+		p.ClosePrice *= rate
+		p.Vwap *= rate
+		points = append(points, p)
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no %s exchange rates between %s and %s cover the %s prices", currency,
+			first.Time().Format("01/02/2006"), last.Time().Format("01/02/2006"), series.Name)
+	}
+	converted := NewPriceSeries(series.Name, points)
+	r.converted[fxKey{series, currency}] = converted
+	return converted, nil
+}
+
+func (r *FXRates) unknownCurrency(currency string) error {
+	return fmt.Errorf("no exchange rates loaded for %q, must be one of %s", currency, strings.Join(r.Currencies(), ", "))
+}
@@ -0,0 +1,107 @@
+package pricedata
+
+import (
+	"testing"
+	"time"
+)
+
+const fxDay = 86400
+
+// fxBase is 07/01/2022 UTC.
+const fxBase = 1656633600
+
+func fxSeries(name string, start int, prices ...float64) *PriceSeries {
+	points := make([]PricePoint, len(prices))
+	for i, price := range prices {
+		points[i] = PricePoint{Timestamp: int64(fxBase + (start+i)*fxDay), OpenPrice: price, ClosePrice: price}
+	}
+	return NewPriceSeries(name, points)
+}
+
+func fxDate(day int) time.Time {
+	return time.Unix(int64(fxBase+day*fxDay), 0).UTC()
+}
+
+func TestFXRate(t *testing.T) {
+	// EUR rates for days 2 to 4 only.
+	rates := NewFXRates(fxSeries("eur", 2, 0.9, 0.95, 1))
+	tests := []struct {
+		name     string
+		currency string
+		day      int
+		want     float64
+		wantErr  bool
+	}{
+		{"usd", "USD", 0, 1, false},
+		{"empty is usd", "", 0, 1, false},
+		{"exact day", "EUR", 3, 0.95, false},
+		{"lower case", "eur", 2, 0.9, false},
+		{"carried past the end", "EUR", 4 + maxFXCarryDays, 1, false},
+		{"too far past the end", "EUR", 5 + maxFXCarryDays, 0, true},
+		{"before the first rate", "EUR", 1, 0, true},
+		{"unknown currency", "CAD", 3, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Rate(tt.currency, fxDate(tt.day))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rate err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Rate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFXConvert(t *testing.T) {
+	prices := fxSeries("kraken", 0, 100, 200, 300, 400, 500, 600)
+	tests := []struct {
+		name     string
+		rates    *PriceSeries
+		currency string
+		want     []float64
+		wantErr  bool
+	}{
+		{"usd is unchanged", fxSeries("EUR", 0, 2), "USD", []float64{100, 200, 300, 400, 500, 600}, false},
+		{"covering rates", fxSeries("EUR", 0, 2, 2, 2, 2, 2, 2), "EUR", []float64{200, 400, 600, 800, 1000, 1200}, false},
+		{"rates start later", fxSeries("EUR", 3, 0.5, 0.5, 0.5), "EUR", []float64{200, 250, 300}, false},
+		{"gaps carry the last rate", fxSeries("EUR", 0, 2), "EUR", []float64{200, 400, 600, 800, 1000, 1200}, false},
+		{"rates after the prices", fxSeries("EUR", 10, 2), "EUR", nil, true},
+		{"unknown currency", fxSeries("EUR", 0, 2), "CAD", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates := NewFXRates(tt.rates)
+			got, err := rates.Convert(prices, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Convert err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Len() != len(tt.want) {
+				t.Fatalf("converted %d days, want %d", got.Len(), len(tt.want))
+			}
+			for i, p := range got.points {
+				if p.OpenPrice != tt.want[i] || p.ClosePrice != tt.want[i] {
+					t.Fatalf("day %d = %v/%v, want %v", i, p.OpenPrice, p.ClosePrice, tt.want[i])
+				}
+			}
+			again, _ := rates.Convert(prices, tt.currency)
+			if again != got {
+				t.Fatal("Convert didn't reuse the converted series")
+			}
+		})
+	}
+
+	rates := NewFXRates(fxSeries("EUR", 0, 2))
+	long := fxSeries("kraken", 0, make([]float64, 2+maxFXCarryDays)...)
+	converted, err := rates.Convert(long, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.Len() != 1+maxFXCarryDays {
+		t.Fatalf("converted %d days, want the %d the last rate can be carried to", converted.Len(), 1+maxFXCarryDays)
+	}
+}
@@ -0,0 +1,80 @@
+package pricedata
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+// JSONStore keeps prices in the {"data": [{"timestamp", "openPrice", ...}]}
+// layout of PriceDataKraken.json.
+type JSONStore struct {
+	Path    string
+	Backups int
+}
+
+func (s *JSONStore) String() string {
+	return s.Path
+}
+
+func (s *JSONStore) Read() ([]PricePoint, error) {
+	content, err := ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s.Path, err)
+	}
+	return s.decode(content)
+}
+
+func (s *JSONStore) Write(points []PricePoint) error {
+	return s.Update(func([]PricePoint) ([]PricePoint, error) {
+		return points, nil
+	})
+}
+
+func (s *JSONStore) Update(update func(points []PricePoint) ([]PricePoint, error)) error {
+	return UpdateFile(s.Path, s.Backups, func(content []byte) ([]byte, error) {
+		points := []PricePoint{}
+		if len(content) > 0 {
+			var err error
+			if points, err = s.decode(content); err != nil {
+				return nil, err
+			}
+		}
+		updated, err := update(points)
+		if err != nil {
+			return nil, err
+		}
+		return s.encode(updated)
+	})
+}
+
+func (s *JSONStore) decode(content []byte) ([]PricePoint, error) {
+	if !gjson.ValidBytes(content) {
+		return nil, fmt.Errorf("error parsing %s: invalid json", s.Path)
+	}
+	vals := gjson.GetBytes(content, "data").Array()
+	points := make([]PricePoint, 0, len(vals))
+	for _, v := range vals {
+		points = append(points, PricePoint{
+			Timestamp:  v.Get("timestamp").Int(),
+			OpenPrice:  v.Get("openPrice").Float(),
+			HighPrice:  v.Get("highPrice").Float(),
+			LowPrice:   v.Get("lowPrice").Float(),
+			ClosePrice: v.Get("closePrice").Float(),
+			Volume:     v.Get("volume").Float(),
+			Vwap:       v.Get("vwap").Float(),
+		})
+	}
+	return points, nil
+}
+
+func (s *JSONStore) encode(points []PricePoint) ([]byte, error) {
+	content, err := json.MarshalIndent(struct {
+		Data []PricePoint `json:"data"`
+	}{points}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding price data: %w", err)
+	}
+	return append(content, '\n'), nil
+}
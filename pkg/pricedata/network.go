@@ -0,0 +1,187 @@
+package pricedata
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// maxNetworkCarryDays is how long the last difficulty is used past the
+	// end of the data, about one retarget.
+	maxNetworkCarryDays = 14
+
+	// HalvingInterval is how many blocks pass between subsidy halvings.
+	HalvingInterval = 210000
+	initialSubsidy  = 50.0
+)
+
+// BlockSubsidy returns the block subsidy in BTC at height, halving every
+// HalvingInterval blocks.
+func BlockSubsidy(height int64) float64 {
+	if height < 0 {
+		return 0
+	}
+	return initialSubsidy / math.Pow(2, float64(height/HalvingInterval))
+}
+
+// NetworkPoint is the bitcoin network's mining difficulty and block subsidy
+// on one day. Height is the block height it was taken at, when known.
+type NetworkPoint struct {
+	Timestamp  int64   `json:"timestamp"`
+	Height     int64   `json:"height,omitempty"`
+	Difficulty float64 `json:"difficulty"`
+	Subsidy    float64 `json:"subsidy"`
+}
+
+// Time returns the point's timestamp as a UTC time.
+func (p NetworkPoint) Time() time.Time {
+	return time.Unix(p.Timestamp, 0).UTC()
+}
+
+// ExpectedCoins returns the BTC a miner with hashrateTHs terahashes per
+// second running uptimePercent of the day should earn from block subsidies:
+// its share of the day's hashes over the hashes a block takes at this
+// difficulty (difficulty * 2^32), times the subsidy. Fees are left out.
+func (p NetworkPoint) ExpectedCoins(hashrateTHs, uptimePercent float64) float64 {
+	if p.Difficulty <= 0 {
+		return 0
+	}
+	hashes := hashrateTHs * 1e12 * secondsPerDay * uptimePercent / 100
+	return hashes / (p.Difficulty * math.Pow(2, 32)) * p.Subsidy
+}
+
+// NetworkSeries is a daily difficulty and subsidy history sorted by day.
+type NetworkSeries struct {
+	points []NetworkPoint
+}
+
+// NewNetworkSeries builds a series from points in any order. When more than
+// one point falls on the same day the first one wins.
+func NewNetworkSeries(points []NetworkPoint) *NetworkSeries {
+	sorted := make([]NetworkPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dayOf(sorted[i].Timestamp) < dayOf(sorted[j].Timestamp)
+	})
+	deduped := make([]NetworkPoint, 0, len(sorted))
+	for _, p := range sorted {
+		if n := len(deduped); n > 0 && dayOf(deduped[n-1].Timestamp) == dayOf(p.Timestamp) {
+			continue
+		}
+		deduped = append(deduped, p)
+	}
+	return &NetworkSeries{points: deduped}
+}
+
+// LoadNetwork reads a {"data": [{"timestamp", "height", "difficulty",
+// "subsidy"}]} file such as NetworkData.json. Only the days difficulty or the
+// subsidy changed on need a point, since each one is carried forward.
+func LoadNetwork(path string) (*NetworkSeries, error) {
+	content, err := ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return parseNetwork(path, content)
+}
+
+// parseNetwork parses content, what was read from path, as LoadNetwork does.
+func parseNetwork(path string, content []byte) (*NetworkSeries, error) {
+	if !gjson.ValidBytes(content) {
+		return nil, fmt.Errorf("error parsing %s: invalid json", path)
+	}
+	vals := gjson.GetBytes(content, "data").Array()
+	points := make([]NetworkPoint, 0, len(vals))
+	for _, v := range vals {
+		points = append(points, NetworkPoint{
+			Timestamp:  v.Get("timestamp").Int(),
+			Height:     v.Get("height").Int(),
+			Difficulty: v.Get("difficulty").Float(),
+			Subsidy:    v.Get("subsidy").Float(),
+		})
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("error loading %s: no network data", path)
+	}
+	return NewNetworkSeries(points), nil
+}
+
+// UpdateNetwork replaces the points in the network data file at path with
+// whatever update returns for them, under the same lock, atomic write and
+// backups as the price files. A missing file starts out empty.
+func UpdateNetwork(path string, backups int, update func(points []NetworkPoint) ([]NetworkPoint, error)) error {
+	return UpdateFile(path, backups, func(content []byte) ([]byte, error) {
+		points := []NetworkPoint{}
+		if len(content) > 0 {
+			series, err := parseNetwork(path, content)
+			if err != nil {
+				return nil, err
+			}
+			points = series.Points()
+		}
+		updated, err := update(points)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.MarshalIndent(struct {
+			Data []NetworkPoint `json:"data"`
+		}{NewNetworkSeries(updated).points}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error encoding network data: %w", err)
+		}
+		return append(encoded, '\n'), nil
+	})
+}
+
+// Points returns a copy of the series' points in day order.
+func (s *NetworkSeries) Points() []NetworkPoint {
+	points := make([]NetworkPoint, len(s.points))
+	copy(points, s.points)
+	return points
+}
+
+func (s *NetworkSeries) Len() int {
+	return len(s.points)
+}
+
+func (s *NetworkSeries) First() (NetworkPoint, bool) {
+	if len(s.points) == 0 {
+		return NetworkPoint{}, false
+	}
+	return s.points[0], true
+}
+
+func (s *NetworkSeries) Last() (NetworkPoint, bool) {
+	if len(s.points) == 0 {
+		return NetworkPoint{}, false
+	}
+	return s.points[len(s.points)-1], true
+}
+
+// AtOrBefore returns the point for the day containing date, or the latest
+// point before it when that day is missing. Difficulty only changes every
+// 2016 blocks, so the last point is carried forward for up to
+// maxNetworkCarryDays past the end of the data and no further.
+func (s *NetworkSeries) AtOrBefore(date time.Time) (NetworkPoint, bool) {
+	day := Day(date)
+	if last, ok := s.Last(); !ok || day-dayOf(last.Timestamp) > maxNetworkCarryDays {
+		return NetworkPoint{}, false
+	}
+	i := sort.Search(len(s.points), func(i int) bool {
+		return dayOf(s.points[i].Timestamp) > day
+	})
+	if i == 0 {
+		return NetworkPoint{}, false
+	}
+	return s.points[i-1], true
+}
+
+// Covers reports whether AtOrBefore has a difficulty for date.
+func (s *NetworkSeries) Covers(date time.Time) bool {
+	_, ok := s.AtOrBefore(date)
+	return ok
+}
@@ -0,0 +1,216 @@
+package pricedata
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+const (
+	FieldOpen    = "open"
+	FieldClose   = "close"
+	FieldTypical = "typical"
+	FieldVWAP    = "vwap"
+)
+
+// PricePoint is one day of OHLCV data. Files written before high, low, close,
+// volume and vwap were recorded only carry the open price; NewPriceSeries
+// fills in the rest as best it can.
+type PricePoint struct {
+	Timestamp  int64   `json:"timestamp"`
+	OpenPrice  float64 `json:"openPrice"`
+	HighPrice  float64 `json:"highPrice,omitempty"`
+	LowPrice   float64 `json:"lowPrice,omitempty"`
+	ClosePrice float64 `json:"closePrice,omitempty"`
+	Volume     float64 `json:"volume,omitempty"`
+	Vwap       float64 `json:"vwap,omitempty"`
+}
+
+// ValidPriceField returns an error unless field names a price PricePoint.Price
+// knows how to compute. An empty field means open.
+func ValidPriceField(field string) error {
+	switch field {
+	case "", FieldOpen, FieldClose, FieldTypical, FieldVWAP:
+		return nil
+	}
+	return fmt.Errorf("unknown price field %q, expected one of %s, %s, %s or %s", field, FieldOpen, FieldClose, FieldTypical, FieldVWAP)
+}
+
+// Price returns the price a purchase on this day would be made at. Typical is
+// (high + low + close) / 3, which also stands in for vwap when the source had
+// no volume weighted price.
+func (p PricePoint) Price(field string) float64 {
+	switch field {
+	case FieldClose:
+		return p.ClosePrice
+	case FieldTypical:
+		return (p.HighPrice + p.LowPrice + p.ClosePrice) / 3
+	case FieldVWAP:
+		if p.Vwap > 0 {
+			return p.Vwap
+		}
+		return (p.HighPrice + p.LowPrice + p.ClosePrice) / 3
+	}
+	return p.OpenPrice
+}
+
+// Time returns the point's timestamp as a UTC time.
+func (p PricePoint) Time() time.Time {
+	return time.Unix(p.Timestamp, 0).UTC()
+}
+
+// PriceSeries is a daily price history held in memory, sorted and indexed by
+// day so lookups don't have to rescan the underlying file.
+type PriceSeries struct {
+	Name   string
+	points []PricePoint
+}
+
+// Day returns the number of whole days between the unix epoch and t in UTC.
+func Day(t time.Time) int64 {
+	return dayOf(t.Unix())
+}
+
+func dayOf(timestamp int64) int64 {
+	if timestamp < 0 && timestamp%secondsPerDay != 0 {
+		return timestamp/secondsPerDay - 1
+	}
+	return timestamp / secondsPerDay
+}
+
+// Load reads a source's price history from its store.
+func Load(name string, store PriceStore) (*PriceSeries, error) {
+	points, err := store.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("error loading %s: no price data", store)
+	}
+	return NewPriceSeries(name, points), nil
+}
+
+// NewPriceSeries builds a series from points in any order. When more than one
+// point falls on the same day the first one wins. A missing close is taken
+// from the next day's open, or the day's own open on the last day, and a
+// missing high or low from the larger or smaller of open and close.
+func NewPriceSeries(name string, points []PricePoint) *PriceSeries {
+	sorted := make([]PricePoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dayOf(sorted[i].Timestamp) < dayOf(sorted[j].Timestamp)
+	})
+
+	deduped := make([]PricePoint, 0, len(sorted))
+	for _, p := range sorted {
+		if n := len(deduped); n > 0 && dayOf(deduped[n-1].Timestamp) == dayOf(p.Timestamp) {
+			continue
+		}
+		deduped = append(deduped, p)
+	}
+
+	for i := range deduped {
+		p := &deduped[i]
+		if p.ClosePrice == 0 {
+			p.ClosePrice = p.OpenPrice
+			if i+1 < len(deduped) && dayOf(deduped[i+1].Timestamp) == dayOf(p.Timestamp)+1 {
+				p.ClosePrice = deduped[i+1].OpenPrice
+			}
+		}
+		if p.HighPrice == 0 {
+			p.HighPrice = math.Max(p.OpenPrice, p.ClosePrice)
+		}
+		if p.LowPrice == 0 {
+			p.LowPrice = math.Min(p.OpenPrice, p.ClosePrice)
+		}
+	}
+	return &PriceSeries{Name: name, points: deduped}
+}
+
+func (s *PriceSeries) Len() int {
+	return len(s.points)
+}
+
+// Points returns a copy of every point in the series.
+func (s *PriceSeries) Points() []PricePoint {
+	points := make([]PricePoint, len(s.points))
+	copy(points, s.points)
+	return points
+}
+
+func (s *PriceSeries) First() (PricePoint, bool) {
+	if len(s.points) == 0 {
+		return PricePoint{}, false
+	}
+	return s.points[0], true
+}
+
+func (s *PriceSeries) Last() (PricePoint, bool) {
+	if len(s.points) == 0 {
+		return PricePoint{}, false
+	}
+	return s.points[len(s.points)-1], true
+}
+
+// search returns the index of the first point on or after day.
+func (s *PriceSeries) search(day int64) int {
+	return sort.Search(len(s.points), func(i int) bool {
+		return dayOf(s.points[i].Timestamp) >= day
+	})
+}
+
+// At returns the point for the day containing date.
+func (s *PriceSeries) At(date time.Time) (PricePoint, bool) {
+	day := Day(date)
+	i := s.search(day)
+	if i < len(s.points) && dayOf(s.points[i].Timestamp) == day {
+		return s.points[i], true
+	}
+	return PricePoint{}, false
+}
+
+// AtOrBefore returns the point for the day containing date, or the latest
+// point before it when that day is missing.
+func (s *PriceSeries) AtOrBefore(date time.Time) (PricePoint, bool) {
+	day := Day(date)
+	i := s.search(day)
+	if i < len(s.points) && dayOf(s.points[i].Timestamp) == day {
+		return s.points[i], true
+	}
+	if i == 0 {
+		return PricePoint{}, false
+	}
+	return s.points[i-1], true
+}
+
+// Range returns the points for every day from start through end inclusive.
+// Days missing from the series are skipped.
+func (s *PriceSeries) Range(start, end time.Time) []PricePoint {
+	from := s.search(Day(start))
+	to := s.search(Day(end) + 1)
+	if from >= to {
+		return []PricePoint{}
+	}
+	points := make([]PricePoint, to-from)
+	copy(points, s.points[from:to])
+	return points
+}
+
+// Prices returns the open prices for every day from start through end inclusive.
+func (s *PriceSeries) Prices(start, end time.Time) []float64 {
+	return s.FieldPrices(FieldOpen, start, end)
+}
+
+// FieldPrices returns the given price field for every day from start through
+// end inclusive.
+func (s *PriceSeries) FieldPrices(field string, start, end time.Time) []float64 {
+	points := s.Range(start, end)
+	prices := make([]float64, 0, len(points))
+	for _, p := range points {
+		prices = append(prices, p.Price(field))
+	}
+	return prices
+}
@@ -0,0 +1,236 @@
+package pricedata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SourceKraken   = "kraken"
+	SourceCoinbase = "coinbase"
+	SourceMedian   = "median"
+	SourceBlended  = "blended"
+)
+
+// Sources holds every loaded exchange series plus the series derived from
+// them, keyed by the name a request uses to pick one.
+type Sources struct {
+	// Version identifies the data the sources were loaded from, so responses
+	// can say which price data they were computed against.
+	Version string
+	// FX converts the exchange series, which are quoted in USD, to other
+	// currencies.
+	FX *FXRates
+	// Network is the difficulty and subsidy history expected mining is
+	// worked out from. It is empty when none is configured.
+	Network       *NetworkSeries
+	defaultSource string
+	series        map[string]*PriceSeries
+}
+
+// NewSources builds the median and blended series from the given exchange
+// series. The first series is the default. Weights are keyed by series name;
+// a series without a weight counts as 1.
+func NewSources(weights map[string]float64, series ...*PriceSeries) (*Sources, error) {
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no price series loaded")
+	}
+	s := &Sources{
+		FX:            NewFXRates(),
+		Network:       NewNetworkSeries(nil),
+		defaultSource: series[0].Name,
+		series:        make(map[string]*PriceSeries, len(series)+2),
+	}
+	for _, ps := range series {
+		if _, ok := s.series[ps.Name]; ok {
+			return nil, fmt.Errorf("duplicate price source %q", ps.Name)
+		}
+		s.series[ps.Name] = ps
+	}
+	s.series[SourceMedian] = Median(SourceMedian, series...)
+	s.series[SourceBlended] = Blend(SourceBlended, weights, series...)
+	return s, nil
+}
+
+// Get returns the named series, or the default series when name is empty.
+func (s *Sources) Get(name string) (*PriceSeries, error) {
+	if name == "" {
+		name = s.defaultSource
+	}
+	ps, ok := s.series[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown price source %q, expected one of %s", name, strings.Join(s.Names(), ", "))
+	}
+	return ps, nil
+}
+
+func (s *Sources) Default() string {
+	return s.defaultSource
+}
+
+// LastDate returns the last day the default source has a price for.
+func (s *Sources) LastDate() time.Time {
+	last, _ := s.series[s.defaultSource].Last()
+	return last.Time()
+}
+
+// Validate checks that freshly loaded sources are fit to replace previous:
+// every exchange series still exists, isn't empty and hasn't lost days off
+// its end.
+func (s *Sources) Validate(previous *Sources) error {
+	for name, ps := range s.series {
+		if ps.Len() == 0 {
+			return fmt.Errorf("price source %s is empty", name)
+		}
+	}
+	if previous == nil {
+		return nil
+	}
+	for name, old := range previous.series {
+		ps, ok := s.series[name]
+		if !ok {
+			return fmt.Errorf("price source %s is missing", name)
+		}
+		oldLast, _ := old.Last()
+		newLast, _ := ps.Last()
+		if newLast.Timestamp < oldLast.Timestamp {
+			return fmt.Errorf("price source %s now ends %s, before %s", name, formatDay(newLast.Timestamp), formatDay(oldLast.Timestamp))
+		}
+	}
+	return nil
+}
+
+func (s *Sources) Names() []string {
+	names := make([]string, 0, len(s.series))
+	for name := range s.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dailyPrices groups the points of every series by day.
+func dailyPrices(series ...*PriceSeries) (map[int64][]PricePoint, []int64) {
+	byDay := map[int64][]PricePoint{}
+	for _, ps := range series {
+		for _, p := range ps.points {
+			day := dayOf(p.Timestamp)
+			byDay[day] = append(byDay[day], p)
+		}
+	}
+	days := make([]int64, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	return byDay, days
+}
+
+// priceFields lists accessors for every price on a point so median and blend
+// can combine them field by field.
+var priceFields = []func(p *PricePoint) *float64{
+	func(p *PricePoint) *float64 { return &p.OpenPrice },
+	func(p *PricePoint) *float64 { return &p.HighPrice },
+	func(p *PricePoint) *float64 { return &p.LowPrice },
+	func(p *PricePoint) *float64 { return &p.ClosePrice },
+	func(p *PricePoint) *float64 { return &p.Vwap },
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	m := values[len(values)/2]
+	if len(values)%2 == 0 {
+		m = (values[len(values)/2-1] + m) / 2
+	}
+	return m
+}
+
+// Median returns a series holding, for each day, the median of each price
+// field across every series that has that day, and their summed volume.
+func Median(name string, series ...*PriceSeries) *PriceSeries {
+	byDay, days := dailyPrices(series...)
+	points := make([]PricePoint, 0, len(days))
+	for _, day := range days {
+		point := PricePoint{Timestamp: day * secondsPerDay}
+		for _, field := range priceFields {
+			values := make([]float64, 0, len(byDay[day]))
+			for i := range byDay[day] {
+				if v := *field(&byDay[day][i]); v > 0 {
+					values = append(values, v)
+				}
+			}
+			*field(&point) = median(values)
+		}
+		for _, p := range byDay[day] {
+			point.Volume += p.Volume
+		}
+		points = append(points, point)
+	}
+	return &PriceSeries{Name: name, points: points}
+}
+
+// Blend returns a series holding, for each day, the weighted average of each
+// price field across every series that has that day. When every series
+// reports volume for the day its weight is scaled by that volume, so the
+// blend leans towards where the trading happened.
+func Blend(name string, weights map[string]float64, series ...*PriceSeries) *PriceSeries {
+	type weighted struct {
+		point  PricePoint
+		weight float64
+	}
+	byDay := map[int64][]weighted{}
+	for _, ps := range series {
+		w := 1.0
+		if configured, ok := weights[ps.Name]; ok {
+			w = configured
+		}
+		if w <= 0 {
+			continue
+		}
+		for _, p := range ps.points {
+			day := dayOf(p.Timestamp)
+			byDay[day] = append(byDay[day], weighted{p, w})
+		}
+	}
+
+	points := make([]PricePoint, 0, len(byDay))
+	for day, entries := range byDay {
+		useVolume := true
+		for _, e := range entries {
+			if e.point.Volume <= 0 {
+				useVolume = false
+			}
+		}
+
+		point := PricePoint{Timestamp: day * secondsPerDay}
+		for _, field := range priceFields {
+			sum, total := 0.0, 0.0
+			for i := range entries {
+				v := *field(&entries[i].point)
+				if v <= 0 {
+					continue
+				}
+				w := entries[i].weight
+				if useVolume {
+					w *= entries[i].point.Volume
+				}
+				sum += v * w
+				total += w
+			}
+			if total > 0 {
+				*field(&point) = sum / total
+			}
+		}
+		for _, e := range entries {
+			point.Volume += e.point.Volume
+		}
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
+	return &PriceSeries{Name: name, points: points}
+}
@@ -0,0 +1,149 @@
+package pricedata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS prices (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	source TEXT NOT NULL,
+	timestamp INTEGER NOT NULL,
+	open_price REAL NOT NULL,
+	high_price REAL NOT NULL DEFAULT 0,
+	low_price REAL NOT NULL DEFAULT 0,
+	close_price REAL NOT NULL DEFAULT 0,
+	volume REAL NOT NULL DEFAULT 0,
+	vwap REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS prices_source_timestamp ON prices (source, timestamp);`
+
+// SQLiteStore keeps prices for any number of sources in a single SQLite
+// database file. Rows keep their insertion order so Read returns them the way
+// they were written.
+type SQLiteStore struct {
+	Path   string
+	Source string
+}
+
+func (s *SQLiteStore) String() string {
+	return fmt.Sprintf("%s (%s)", s.Path, s.Source)
+}
+
+func (s *SQLiteStore) open() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", s.Path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating schema in %s: %w", s.Path, err)
+	}
+	return db, nil
+}
+
+func (s *SQLiteStore) Read() ([]PricePoint, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s, err)
+	}
+	defer tx.Rollback()
+	return s.read(tx)
+}
+
+func (s *SQLiteStore) Write(points []PricePoint) error {
+	return s.Update(func([]PricePoint) ([]PricePoint, error) {
+		return points, nil
+	})
+}
+
+// Update runs inside one immediate transaction, which takes SQLite's write
+// lock up front so concurrent updaters queue instead of overwriting each other.
+func (s *SQLiteStore) Update(update func(points []PricePoint) ([]PricePoint, error)) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", s, err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("error writing %s: %w", s, err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+		}
+	}()
+
+	points, err := s.read(conn)
+	if err != nil {
+		return err
+	}
+	updated, err := update(points)
+	if err != nil {
+		return err
+	}
+	if err := s.write(conn, updated); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(context.Background(), "COMMIT"); err != nil {
+		return fmt.Errorf("error writing %s: %w", s, err)
+	}
+	committed = true
+	return nil
+}
+
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQLiteStore) read(q sqlQuerier) ([]PricePoint, error) {
+	rows, err := q.QueryContext(context.Background(), `SELECT timestamp, open_price, high_price, low_price, close_price, volume, vwap
+		FROM prices WHERE source = ? ORDER BY id`, s.Source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s, err)
+	}
+	defer rows.Close()
+
+	points := []PricePoint{}
+	for rows.Next() {
+		var p PricePoint
+		if err := rows.Scan(&p.Timestamp, &p.OpenPrice, &p.HighPrice, &p.LowPrice, &p.ClosePrice, &p.Volume, &p.Vwap); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", s, err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s, err)
+	}
+	return points, nil
+}
+
+func (s *SQLiteStore) write(q sqlQuerier, points []PricePoint) error {
+	if _, err := q.ExecContext(context.Background(), `DELETE FROM prices WHERE source = ?`, s.Source); err != nil {
+		return fmt.Errorf("error writing %s: %w", s, err)
+	}
+	for _, p := range points {
+		if _, err := q.ExecContext(context.Background(), `INSERT INTO prices (source, timestamp, open_price, high_price, low_price, close_price, volume, vwap)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, s.Source, p.Timestamp, p.OpenPrice, p.HighPrice, p.LowPrice, p.ClosePrice, p.Volume, p.Vwap); err != nil {
+			return fmt.Errorf("error writing %s: %w", s, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package pricedata
+
+import (
+	"fmt"
+)
+
+const (
+	BackendJSON   = "json"
+	BackendCSV    = "csv"
+	BackendSQLite = "sqlite"
+)
+
+// PriceStore is where a source's daily price history is kept. Read returns
+// points in stored order, without sorting or removing duplicates, so audits
+// see the data exactly as it was written. Write replaces everything stored.
+// Update replaces everything with what update returns for the current points,
+// holding the store's write lock throughout so concurrent updates can't lose
+// each other's changes.
+type PriceStore interface {
+	Read() ([]PricePoint, error)
+	Write(points []PricePoint) error
+	Update(update func(points []PricePoint) ([]PricePoint, error)) error
+	String() string
+}
+
+// NewStore returns the store for a source kept by backend at path. An empty
+// backend means json. The sqlite backend keeps every source in one database
+// file, so source picks which one this store reads and writes. File backends
+// keep the given number of rotated backups when they write; sqlite relies on
+// its own transactions instead.
+func NewStore(backend, path, source string, backups int) (PriceStore, error) {
+	switch backend {
+	case "", BackendJSON:
+		return &JSONStore{Path: path, Backups: backups}, nil
+	case BackendCSV:
+		return &CSVStore{Path: path, Backups: backups}, nil
+	case BackendSQLite:
+		return &SQLiteStore{Path: path, Source: source}, nil
+	}
+	return nil, fmt.Errorf("unknown price store backend %q, expected one of %s, %s or %s", backend, BackendJSON, BackendCSV, BackendSQLite)
+}
@@ -0,0 +1,97 @@
+package pricedata
+
+import (
+	"Mining-Profitability/pkg/config"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var storePoints = []PricePoint{
+	{Timestamp: 1656633600, OpenPrice: 19800, HighPrice: 20100, LowPrice: 19500, ClosePrice: 19950, Volume: 1200, Vwap: 19820},
+	{Timestamp: 1656720000, OpenPrice: 19950},
+	{Timestamp: 1656547200, OpenPrice: 20100, ClosePrice: 19800},
+}
+
+func TestStoresRoundTrip(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendCSV, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(backend, filepath.Join(t.TempDir(), "prices."+backend), SourceKraken, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Write(storePoints); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			got, err := store.Read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, storePoints) {
+				t.Fatalf("Read = %+v, want the points as written, in order: %+v", got, storePoints)
+			}
+
+			err = store.Update(func(points []PricePoint) ([]PricePoint, error) {
+				return append(points, PricePoint{Timestamp: 1656806400, OpenPrice: 20500}), nil
+			})
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			got, err = store.Read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if len(got) != len(storePoints)+1 || got[len(got)-1].OpenPrice != 20500 {
+				t.Fatalf("after Update got %+v, want the new day appended", got)
+			}
+		})
+	}
+	if _, err := NewStore("yaml", "prices.yaml", SourceKraken, 0); err == nil {
+		t.Fatal("want an error for an unknown backend")
+	}
+}
+
+func TestLoadSourcesVersionMatchesParsedData(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		PriceDataKrakenPath:   filepath.Join(dir, "kraken.json"),
+		PriceDataCoinbasePath: filepath.Join(dir, "coinbase.json"),
+	}
+	for _, path := range []string{cfg.PriceDataKrakenPath, cfg.PriceDataCoinbasePath} {
+		if err := (&JSONStore{Path: path}).Write(storePoints); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sources, err := LoadSources(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := Checksum(ConfiguredPaths(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sources.Version != version {
+		t.Fatalf("Version = %s, want the checksum of the files, %s", sources.Version, version)
+	}
+	series, err := sources.Get(SourceKraken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series.Len() != 3 {
+		t.Fatalf("loaded %d days, want 3", series.Len())
+	}
+	if first, _ := series.First(); first.Timestamp != 1656547200 {
+		t.Fatalf("first day = %d, want the points sorted", first.Timestamp)
+	}
+
+	if err := (&JSONStore{Path: cfg.PriceDataKrakenPath}).Write(storePoints[:2]); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := Checksum(ConfiguredPaths(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == version {
+		t.Fatal("Checksum didn't change after the data did")
+	}
+}
@@ -0,0 +1,36 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlockchainInfoProvider only serves the current price, from the tobtc
+// endpoint for market currency, e.g. USD.
+type BlockchainInfoProvider struct {
+	httpProvider
+}
+
+func (p *BlockchainInfoProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	return nil, fmt.Errorf("blockchain.info history: %w", ErrUnsupported)
+}
+
+// Current asks how much BTC 500 units of currency buys and inverts it.
+func (p *BlockchainInfoProvider) Current() (float64, error) {
+	body, err := p.get("/tobtc", url.Values{"currency": {p.market}, "value": {"500"}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	btc, err := strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing blockchain.info response %q: %w", body, err)
+	}
+	if btc <= 0 {
+		return 0, fmt.Errorf("error parsing blockchain.info response %q: not a positive amount", body)
+	}
+	return 500 / btc, nil
+}
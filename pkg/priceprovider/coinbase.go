@@ -0,0 +1,83 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// coinbaseChunkDays is the most candles Coinbase returns per request.
+const coinbaseChunkDays = 300
+
+// CoinbaseProvider reads the Coinbase Exchange product candles and ticker,
+// e.g. market BTC-USD.
+type CoinbaseProvider struct {
+	httpProvider
+}
+
+func (p *CoinbaseProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	points := []pricedata.PricePoint{}
+	for from := Midnight(start); !from.After(end); from = from.AddDate(0, 0, coinbaseChunkDays) {
+		to := from.AddDate(0, 0, coinbaseChunkDays-1)
+		if to.After(end) {
+			to = end
+		}
+		body, err := p.get(fmt.Sprintf("/products/%s/candles", url.PathEscape(p.market)), url.Values{
+			"granularity": {"86400"},
+			"start":       {from.Format(time.RFC3339)},
+			"end":         {to.Format(time.RFC3339)},
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := parseCoinbaseHistory(body)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, chunk...)
+	}
+	return inRange(points, start, end), nil
+}
+
+// parseCoinbaseHistory reads a newest-first array of
+// [time, low, high, open, close, volume] rows.
+func parseCoinbaseHistory(body []byte) ([]pricedata.PricePoint, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("error parsing coinbase response: invalid json")
+	}
+	parsed := gjson.ParseBytes(body)
+	if !parsed.IsArray() {
+		return nil, fmt.Errorf("error parsing coinbase response: %s", parsed.Get("message").String())
+	}
+	points := []pricedata.PricePoint{}
+	for _, v := range parsed.Array() {
+		row := v.Array()
+		if len(row) < 6 {
+			return nil, fmt.Errorf("error parsing coinbase response: short row %s", v.Raw)
+		}
+		points = append(points, pricedata.PricePoint{
+			Timestamp:  row[0].Int(),
+			LowPrice:   row[1].Float(),
+			HighPrice:  row[2].Float(),
+			OpenPrice:  row[3].Float(),
+			ClosePrice: row[4].Float(),
+			Volume:     row[5].Float(),
+		})
+	}
+	return points, nil
+}
+
+func (p *CoinbaseProvider) Current() (float64, error) {
+	body, err := p.get(fmt.Sprintf("/products/%s/ticker", url.PathEscape(p.market)), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	price := gjson.GetBytes(body, "price")
+	if !price.Exists() {
+		return 0, fmt.Errorf("error parsing coinbase response: no price")
+	}
+	return price.Float(), nil
+}
@@ -0,0 +1,103 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// CoinGeckoProvider reads the market_chart/range and simple/price endpoints,
+// e.g. market bitcoin, priced in USD.
+type CoinGeckoProvider struct {
+	httpProvider
+}
+
+func (p *CoinGeckoProvider) header() http.Header {
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("x-cg-demo-api-key", p.apiKey)
+	}
+	return header
+}
+
+func (p *CoinGeckoProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	body, err := p.get(fmt.Sprintf("/coins/%s/market_chart/range", url.PathEscape(p.market)), url.Values{
+		"vs_currency": {"usd"},
+		"from":        {strconv.FormatInt(Midnight(start).Unix(), 10)},
+		"to":          {strconv.FormatInt(Midnight(end).AddDate(0, 0, 1).Unix()-1, 10)},
+	}, p.header())
+	if err != nil {
+		return nil, err
+	}
+	points, err := parseCoinGeckoHistory(body)
+	if err != nil {
+		return nil, err
+	}
+	return inRange(points, start, end), nil
+}
+
+// parseCoinGeckoHistory turns the prices and total_volumes arrays of
+// [timestamp ms, value] into daily bars. CoinGecko returns one sample a day
+// for long ranges and hourly samples for ranges under 90 days, so each day's
+// bar is built from whatever samples fall on it: the first is the open, the
+// last the close. Volume is CoinGecko's rolling 24h volume at the first sample.
+func parseCoinGeckoHistory(body []byte) ([]pricedata.PricePoint, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("error parsing coingecko response: invalid json")
+	}
+	prices := gjson.GetBytes(body, "prices")
+	if !prices.Exists() {
+		return nil, fmt.Errorf("error parsing coingecko response: no prices %s", gjson.GetBytes(body, "error").String())
+	}
+
+	volumes := map[int64]float64{}
+	for _, v := range gjson.GetBytes(body, "total_volumes").Array() {
+		day := pricedata.Day(time.UnixMilli(v.Get("0").Int()))
+		if _, ok := volumes[day]; !ok {
+			volumes[day] = v.Get("1").Float()
+		}
+	}
+
+	points := []pricedata.PricePoint{}
+	index := map[int64]int{}
+	for _, v := range prices.Array() {
+		t := time.UnixMilli(v.Get("0").Int())
+		price := v.Get("1").Float()
+		day := pricedata.Day(t)
+		i, ok := index[day]
+		if !ok {
+			index[day] = len(points)
+			points = append(points, pricedata.PricePoint{
+				Timestamp:  Midnight(t).Unix(),
+				OpenPrice:  price,
+				HighPrice:  price,
+				LowPrice:   price,
+				ClosePrice: price,
+				Volume:     volumes[day],
+			})
+			continue
+		}
+		points[i].HighPrice = math.Max(points[i].HighPrice, price)
+		points[i].LowPrice = math.Min(points[i].LowPrice, price)
+		points[i].ClosePrice = price
+	}
+	return points, nil
+}
+
+func (p *CoinGeckoProvider) Current() (float64, error) {
+	body, err := p.get("/simple/price", url.Values{"ids": {p.market}, "vs_currencies": {"usd"}}, p.header())
+	if err != nil {
+		return 0, err
+	}
+	price := gjson.GetBytes(body, p.market+".usd")
+	if !price.Exists() {
+		return 0, fmt.Errorf("error parsing coingecko response: no %s.usd", p.market)
+	}
+	return price.Float(), nil
+}
@@ -0,0 +1,58 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fallback tries each provider in order and returns the first answer. A
+// history request that comes back empty counts as a failure so the next
+// provider gets a chance to fill the range.
+type Fallback struct {
+	Providers []PriceProvider
+}
+
+func (f *Fallback) Name() string {
+	names := make([]string, 0, len(f.Providers))
+	for _, p := range f.Providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *Fallback) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	errs := []string{}
+	for _, p := range f.Providers {
+		points, err := p.History(start, end)
+		if err == nil && len(points) == 0 {
+			err = fmt.Errorf("no prices between %s and %s", start.Format(dateLayout), end.Format(dateLayout))
+		}
+		if err == nil {
+			return points, nil
+		}
+		if !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Sprintf("%s: %s", p.Name(), err.Error()))
+		}
+	}
+	return nil, fmt.Errorf("error getting price history from every provider: %s", strings.Join(errs, "; "))
+}
+
+func (f *Fallback) Current() (float64, error) {
+	errs := []string{}
+	for _, p := range f.Providers {
+		price, err := p.Current()
+		if err == nil && price <= 0 {
+			err = fmt.Errorf("got price %v", price)
+		}
+		if err == nil {
+			return price, nil
+		}
+		if !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Sprintf("%s: %s", p.Name(), err.Error()))
+		}
+	}
+	return 0, fmt.Errorf("error getting current price from every provider: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,107 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// DefaultFXRateUrl serves the European Central Bank's daily reference rates.
+const DefaultFXRateUrl = "https://api.frankfurter.app"
+
+// FrankfurterProvider reads the ECB reference rates the Frankfurter API
+// serves as daily bars of how many units of market, a currency code such as
+// EUR, one USD bought, the layout fxRatePaths files use. The ECB publishes on
+// working days only, so weekends and holidays are missing.
+type FrankfurterProvider struct {
+	httpProvider
+}
+
+// NewFXProvider returns the exchange rate provider for currency at the
+// config's fxRateUrl, or at DefaultFXRateUrl when it has none.
+func NewFXProvider(cfg *config.Config, currency string) (*FrankfurterProvider, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == pricedata.BaseCurrency {
+		return nil, fmt.Errorf("exchange rates are for a currency other than %s", pricedata.BaseCurrency)
+	}
+	baseUrl := cfg.FxRateUrl
+	if baseUrl == "" {
+		baseUrl = DefaultFXRateUrl
+	}
+	return &FrankfurterProvider{httpProvider{
+		name:    "frankfurter",
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		market:  currency,
+		client: &http.Client{
+			Timeout: time.Second * 60,
+		},
+	}}, nil
+}
+
+func (p *FrankfurterProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	body, err := p.get(fmt.Sprintf("/%s..%s", Midnight(start).Format(dateLayout), Midnight(end).Format(dateLayout)), url.Values{
+		"from": {pricedata.BaseCurrency},
+		"to":   {p.market},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	points, err := parseFrankfurterHistory(body, p.market)
+	if err != nil {
+		return nil, err
+	}
+	return inRange(points, start, end), nil
+}
+
+// parseFrankfurterHistory turns rates, keyed by day and then currency, into
+// bars whose every price is that day's rate.
+func parseFrankfurterHistory(body []byte, currency string) ([]pricedata.PricePoint, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("error parsing frankfurter response: invalid json")
+	}
+	rates := gjson.GetBytes(body, "rates")
+	if !rates.Exists() {
+		return nil, fmt.Errorf("error parsing frankfurter response: no rates %s", gjson.GetBytes(body, "message").String())
+	}
+	points := []pricedata.PricePoint{}
+	var err error
+	rates.ForEach(func(key, value gjson.Result) bool {
+		var day time.Time
+		if day, err = time.Parse(dateLayout, key.String()); err != nil {
+			err = fmt.Errorf("error parsing frankfurter response: %w", err)
+			return false
+		}
+		rate := value.Get(currency).Float()
+		points = append(points, pricedata.PricePoint{
+			Timestamp:  day.Unix(),
+			OpenPrice:  rate,
+			HighPrice:  rate,
+			LowPrice:   rate,
+			ClosePrice: rate,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// Current returns the latest reference rate.
+func (p *FrankfurterProvider) Current() (float64, error) {
+	body, err := p.get("/latest", url.Values{"from": {pricedata.BaseCurrency}, "to": {p.market}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	rate := gjson.GetBytes(body, "rates."+p.market)
+	if !rate.Exists() {
+		return 0, fmt.Errorf("error parsing frankfurter response: no rates.%s", p.market)
+	}
+	return rate.Float(), nil
+}
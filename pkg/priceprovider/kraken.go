@@ -0,0 +1,99 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// KrakenProvider reads Kraken's public OHLC and Ticker endpoints, e.g. market
+// XBTUSD. Kraken only serves the most recent 720 daily bars, so older history
+// has to come from another provider.
+type KrakenProvider struct {
+	httpProvider
+}
+
+// krakenResult returns the pair's entry under result, whose key is Kraken's
+// own name for the pair (XXBTZUSD for XBTUSD), after checking the error list.
+func krakenResult(body []byte) (gjson.Result, error) {
+	if !gjson.ValidBytes(body) {
+		return gjson.Result{}, fmt.Errorf("error parsing kraken response: invalid json")
+	}
+	if errs := gjson.GetBytes(body, "error").Array(); len(errs) > 0 {
+		return gjson.Result{}, fmt.Errorf("kraken returned error: %s", errs[0].String())
+	}
+	var pair gjson.Result
+	gjson.GetBytes(body, "result").ForEach(func(key, value gjson.Result) bool {
+		if key.String() == "last" {
+			return true
+		}
+		pair = value
+		return false
+	})
+	if !pair.Exists() {
+		return gjson.Result{}, fmt.Errorf("error parsing kraken response: no pair in result")
+	}
+	return pair, nil
+}
+
+func (p *KrakenProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	body, err := p.get("/0/public/OHLC", url.Values{
+		"pair":     {p.market},
+		"interval": {"1440"},
+		"since":    {strconv.FormatInt(Midnight(start).Unix()-1, 10)},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	points, err := parseKrakenHistory(body)
+	if err != nil {
+		return nil, err
+	}
+	return inRange(points, start, end), nil
+}
+
+// parseKrakenHistory reads rows of
+// [time, "open", "high", "low", "close", "vwap", "volume", count].
+func parseKrakenHistory(body []byte) ([]pricedata.PricePoint, error) {
+	pair, err := krakenResult(body)
+	if err != nil {
+		return nil, err
+	}
+	points := []pricedata.PricePoint{}
+	for _, v := range pair.Array() {
+		row := v.Array()
+		if len(row) < 7 {
+			return nil, fmt.Errorf("error parsing kraken response: short row %s", v.Raw)
+		}
+		points = append(points, pricedata.PricePoint{
+			Timestamp:  row[0].Int(),
+			OpenPrice:  row[1].Float(),
+			HighPrice:  row[2].Float(),
+			LowPrice:   row[3].Float(),
+			ClosePrice: row[4].Float(),
+			Vwap:       row[5].Float(),
+			Volume:     row[6].Float(),
+		})
+	}
+	return points, nil
+}
+
+func (p *KrakenProvider) Current() (float64, error) {
+	body, err := p.get("/0/public/Ticker", url.Values{"pair": {p.market}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	pair, err := krakenResult(body)
+	if err != nil {
+		return 0, err
+	}
+	price := pair.Get("c.0")
+	if !price.Exists() {
+		return 0, fmt.Errorf("error parsing kraken response: no last trade price")
+	}
+	return price.Float(), nil
+}
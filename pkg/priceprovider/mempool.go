@@ -0,0 +1,175 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// DefaultNetworkDataUrl is mempool.space's REST API.
+const DefaultNetworkDataUrl = "https://mempool.space/api"
+
+// MempoolProvider reads the network's difficulty history from a mempool.space
+// API: one point for every retarget, every halving and the current tip, the
+// days difficulty or the subsidy changed on.
+type MempoolProvider struct {
+	httpProvider
+}
+
+// NewNetworkProvider returns the network data provider at the config's
+// networkDataUrl, or at DefaultNetworkDataUrl when it has none.
+func NewNetworkProvider(cfg *config.Config) *MempoolProvider {
+	baseUrl := cfg.NetworkDataUrl
+	if baseUrl == "" {
+		baseUrl = DefaultNetworkDataUrl
+	}
+	return &MempoolProvider{httpProvider{
+		name:    "mempool",
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		client: &http.Client{
+			Timeout: time.Second * 60,
+		},
+	}}
+}
+
+// History returns the points from start to end, a zero start meaning the
+// whole history, moved to midnight UTC. Today's point carries the current
+// difficulty at the tip height when end reaches today.
+func (p *MempoolProvider) History(start, end time.Time) ([]pricedata.NetworkPoint, error) {
+	body, err := p.get("/v1/mining/difficulty-adjustments", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	retargets, err := parseDifficultyAdjustments(body)
+	if err != nil {
+		return nil, err
+	}
+	tip, err := p.tipHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	// difficultyAt returns the difficulty set by the last retarget at or
+	// before height.
+	difficultyAt := func(height int64) float64 {
+		i := sort.Search(len(retargets), func(i int) bool { return retargets[i].Height > height })
+		if i == 0 {
+			return 0
+		}
+		return retargets[i-1].Difficulty
+	}
+
+	points := append([]pricedata.NetworkPoint{}, retargets...)
+	// Halvings only change the subsidy, so their blocks are looked up one by
+	// one, skipping those mined before the first retarget listed or the last
+	// one ahead of start.
+	from := retargets[0].Height
+	if !start.IsZero() {
+		for _, r := range retargets {
+			if r.Timestamp < Midnight(start).Unix() {
+				from = r.Height
+			}
+		}
+	}
+	for height := (from/pricedata.HalvingInterval + 1) * pricedata.HalvingInterval; height <= tip; height += pricedata.HalvingInterval {
+		mined, err := p.blockTime(height)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pricedata.NetworkPoint{
+			Timestamp:  mined.Unix(),
+			Height:     height,
+			Difficulty: difficultyAt(height),
+			Subsidy:    pricedata.BlockSubsidy(height),
+		})
+	}
+	points = append(points, pricedata.NetworkPoint{
+		Timestamp:  time.Now().Unix(),
+		Height:     tip,
+		Difficulty: difficultyAt(tip),
+		Subsidy:    pricedata.BlockSubsidy(tip),
+	})
+
+	// when a retarget, halving or the tip share a day, the highest block's
+	// difficulty and subsidy are the day's
+	sort.Slice(points, func(i, j int) bool { return points[i].Height < points[j].Height })
+	first, last := Midnight(start).Unix(), Midnight(end).Unix()
+	byDay := map[int64]pricedata.NetworkPoint{}
+	for _, point := range points {
+		point.Timestamp = Midnight(point.Time()).Unix()
+		if (!start.IsZero() && point.Timestamp < first) || point.Timestamp > last || point.Difficulty <= 0 {
+			continue
+		}
+		byDay[point.Timestamp] = point
+	}
+	kept := make([]pricedata.NetworkPoint, 0, len(byDay))
+	for _, point := range byDay {
+		kept = append(kept, point)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Timestamp < kept[j].Timestamp })
+	return kept, nil
+}
+
+// parseDifficultyAdjustments reads the [time, height, difficulty, change]
+// rows mempool.space lists newest first into points sorted by height.
+func parseDifficultyAdjustments(body []byte) ([]pricedata.NetworkPoint, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("error parsing mempool response: invalid json")
+	}
+	rows := gjson.ParseBytes(body).Array()
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("error parsing mempool response: no difficulty adjustments")
+	}
+	points := make([]pricedata.NetworkPoint, 0, len(rows))
+	for _, row := range rows {
+		values := row.Array()
+		if len(values) < 3 {
+			return nil, fmt.Errorf("error parsing mempool response: difficulty adjustment %s", row.Raw)
+		}
+		height := values[1].Int()
+		points = append(points, pricedata.NetworkPoint{
+			Timestamp:  values[0].Int(),
+			Height:     height,
+			Difficulty: values[2].Float(),
+			Subsidy:    pricedata.BlockSubsidy(height),
+		})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Height < points[j].Height })
+	return points, nil
+}
+
+func (p *MempoolProvider) tipHeight() (int64, error) {
+	body, err := p.get("/blocks/tip/height", nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	height, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing mempool tip height: %w", err)
+	}
+	return height, nil
+}
+
+// blockTime returns when the block at height was mined.
+func (p *MempoolProvider) blockTime(height int64) (time.Time, error) {
+	hash, err := p.get(fmt.Sprintf("/block-height/%d", height), nil, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	body, err := p.get("/block/"+strings.TrimSpace(string(hash)), nil, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	timestamp := gjson.GetBytes(body, "timestamp")
+	if !timestamp.Exists() {
+		return time.Time{}, fmt.Errorf("error parsing mempool block %d: no timestamp", height)
+	}
+	return time.Unix(timestamp.Int(), 0).UTC(), nil
+}
@@ -0,0 +1,90 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// messariChunkDays keeps each request well under Messari's per-request point limit.
+const messariChunkDays = 365
+
+// MessariProvider reads the market time series, e.g. market kraken-btc-usd.
+type MessariProvider struct {
+	httpProvider
+}
+
+func (p *MessariProvider) header() http.Header {
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("x-messari-api-key", p.apiKey)
+	}
+	return header
+}
+
+func (p *MessariProvider) History(start, end time.Time) ([]pricedata.PricePoint, error) {
+	points := []pricedata.PricePoint{}
+	for from := Midnight(start); !from.After(end); from = from.AddDate(0, 0, messariChunkDays) {
+		to := from.AddDate(0, 0, messariChunkDays-1)
+		if to.After(end) {
+			to = end
+		}
+		body, err := p.get(fmt.Sprintf("/markets/%s/metrics/price/time-series", url.PathEscape(p.market)), url.Values{
+			"start":    {from.Format(dateLayout)},
+			"end":      {to.Format(dateLayout)},
+			"interval": {"1d"},
+		}, p.header())
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := parseMessariHistory(body)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, chunk...)
+	}
+	return inRange(points, start, end), nil
+}
+
+// parseMessariHistory reads data.values, whose rows are
+// [timestamp ms, open, high, low, close, volume].
+func parseMessariHistory(body []byte) ([]pricedata.PricePoint, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("error parsing messari response: invalid json")
+	}
+	points := []pricedata.PricePoint{}
+	for _, v := range gjson.GetBytes(body, "data.values").Array() {
+		row := v.Array()
+		if len(row) < 2 {
+			return nil, fmt.Errorf("error parsing messari response: short row %s", v.Raw)
+		}
+		p := pricedata.PricePoint{
+			Timestamp: row[0].Int() / 1000,
+			OpenPrice: row[1].Float(),
+		}
+		if len(row) >= 6 {
+			p.HighPrice = row[2].Float()
+			p.LowPrice = row[3].Float()
+			p.ClosePrice = row[4].Float()
+			p.Volume = row[5].Float()
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func (p *MessariProvider) Current() (float64, error) {
+	body, err := p.get("/assets/btc/metrics/market-data", nil, p.header())
+	if err != nil {
+		return 0, err
+	}
+	price := gjson.GetBytes(body, "data.market_data.price_usd")
+	if !price.Exists() {
+		return 0, fmt.Errorf("error parsing messari response: no data.market_data.price_usd")
+	}
+	return price.Float(), nil
+}
@@ -0,0 +1,177 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	Messari        = "messari"
+	Kraken         = "kraken"
+	Coinbase       = "coinbase"
+	CoinGecko      = "coingecko"
+	BlockchainInfo = "blockchaininfo"
+
+	dateLayout = "2006-01-02"
+)
+
+// ErrUnsupported is returned by providers that can't serve a kind of request,
+// e.g. blockchain.info has no daily history.
+var ErrUnsupported = errors.New("not supported by this provider")
+
+// PriceProvider is a BTC price API. History returns daily bars from start to
+// end inclusive, one per day at midnight UTC in date order; Current returns
+// the latest price.
+type PriceProvider interface {
+	Name() string
+	History(start, end time.Time) ([]pricedata.PricePoint, error)
+	Current() (float64, error)
+}
+
+// New returns the adapter for one configured provider.
+func New(cfg config.PriceProviderConfig) (PriceProvider, error) {
+	base := httpProvider{
+		name:    strings.ToLower(cfg.Name),
+		baseUrl: strings.TrimRight(cfg.BaseUrl, "/"),
+		market:  cfg.Market,
+		apiKey:  cfg.ApiKey,
+		client: &http.Client{
+			Timeout: time.Second * 60,
+		},
+	}
+	if base.baseUrl == "" {
+		return nil, fmt.Errorf("price provider %s has no baseUrl", cfg.Name)
+	}
+	switch base.name {
+	case Messari:
+		return &MessariProvider{base}, nil
+	case Kraken:
+		return &KrakenProvider{base}, nil
+	case Coinbase:
+		return &CoinbaseProvider{base}, nil
+	case CoinGecko:
+		return &CoinGeckoProvider{base}, nil
+	case BlockchainInfo:
+		return &BlockchainInfoProvider{base}, nil
+	}
+	return nil, fmt.Errorf("unknown price provider %q, must be one of %s, %s, %s, %s or %s",
+		cfg.Name, Messari, Kraken, Coinbase, CoinGecko, BlockchainInfo)
+}
+
+// DefaultProviders stand in for priceProviders when a config has none, as
+// configs from before the key don't: the current price from blockchain.info
+// and history from Kraken, directly or through Messari.
+var DefaultProviders = []config.PriceProviderConfig{
+	{Name: BlockchainInfo, BaseUrl: "https://blockchain.info", Market: "USD"},
+	{Name: Kraken, BaseUrl: "https://api.kraken.com", Market: "XBTUSD"},
+	{Name: Messari, BaseUrl: "https://data.messari.io/api/v1", Market: "kraken-btc-usd"},
+}
+
+// Configured returns the configured providers named in names, in that order,
+// or every configured provider in config order when names is empty. Without
+// priceProviders in the config the DefaultProviders are used.
+func Configured(cfg *config.Config, names []string) (*Fallback, error) {
+	configured := cfg.PriceProviders
+	if len(configured) == 0 {
+		configured = DefaultProviders
+	}
+	byName := map[string]config.PriceProviderConfig{}
+	for _, p := range configured {
+		byName[strings.ToLower(p.Name)] = p
+	}
+	if len(names) == 0 {
+		for _, p := range configured {
+			names = append(names, p.Name)
+		}
+	}
+
+	providers := []PriceProvider{}
+	for _, name := range names {
+		p, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("price provider %q is not configured in priceProviders", name)
+		}
+		provider, err := New(p)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no price providers configured")
+	}
+	return &Fallback{Providers: providers}, nil
+}
+
+// httpProvider holds what every adapter needs to call its API. market is the
+// adapter's own name for the BTC pair, e.g. kraken-btc-usd for Messari or
+// XBTUSD for Kraken.
+type httpProvider struct {
+	name    string
+	baseUrl string
+	market  string
+	apiKey  string
+	client  *http.Client
+}
+
+func (p *httpProvider) Name() string {
+	return p.name
+}
+
+func (p *httpProvider) get(path string, query url.Values, header http.Header) ([]byte, error) {
+	u := p.baseUrl + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", u, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	response, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", u, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: status %d: %.200s", u, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// Midnight returns midnight UTC of the day t falls on.
+func Midnight(t time.Time) time.Time {
+	return time.Unix(pricedata.Day(t)*24*60*60, 0).UTC()
+}
+
+// inRange keeps the points from start to end inclusive, moved to midnight UTC
+// and sorted, since most APIs return whatever window they like.
+func inRange(points []pricedata.PricePoint, start, end time.Time) []pricedata.PricePoint {
+	first, last := Midnight(start).Unix(), Midnight(end).Unix()
+	kept := []pricedata.PricePoint{}
+	seen := map[int64]bool{}
+	for _, p := range points {
+		p.Timestamp = Midnight(p.Time()).Unix()
+		if p.Timestamp < first || p.Timestamp > last || p.OpenPrice <= 0 || seen[p.Timestamp] {
+			continue
+		}
+		seen[p.Timestamp] = true
+		kept = append(kept, p)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Timestamp < kept[j].Timestamp })
+	return kept
+}
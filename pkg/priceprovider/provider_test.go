@@ -0,0 +1,265 @@
+package priceprovider
+
+import (
+	"Mining-Profitability/pkg/config"
+	"Mining-Profitability/pkg/pricedata"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	fixtureStart = time.Date(2022, 7, 18, 0, 0, 0, 0, time.UTC)
+	fixtureEnd   = time.Date(2022, 7, 27, 0, 0, 0, 0, time.UTC)
+)
+
+func TestProvidersParseFixtures(t *testing.T) {
+	server := newFixtureServer()
+	defer server.Close()
+
+	for _, cfg := range fixtureConfig(server.URL) {
+		t.Run(cfg.Name, func(t *testing.T) {
+			provider, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			points, err := provider.History(fixtureStart, fixtureEnd)
+			if cfg.Name == BlockchainInfo {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("History error = %v, want ErrUnsupported", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("History: %v", err)
+				}
+				if len(points) != 10 {
+					t.Fatalf("got %d days, want 10", len(points))
+				}
+				if !points[0].Time().Equal(fixtureStart) || !points[9].Time().Equal(fixtureEnd) {
+					t.Fatalf("got %s to %s, want 2022-07-18 to 2022-07-27", points[0].Time(), points[9].Time())
+				}
+				for _, p := range points {
+					if p.OpenPrice <= 0 || p.ClosePrice <= 0 {
+						t.Fatalf("non-positive price on %s: %+v", p.Time(), p)
+					}
+				}
+			}
+			price, err := provider.Current()
+			if err != nil {
+				t.Fatalf("Current: %v", err)
+			}
+			if price < 20000 || price > 25000 {
+				t.Fatalf("Current = %v, want the 07/27/2022 price", price)
+			}
+		})
+	}
+}
+
+func TestFallbackSkipsUpstreamError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	fixtures := newFixtureServer()
+	defer fixtures.Close()
+
+	fallback := &Fallback{Providers: []PriceProvider{
+		&KrakenProvider{httpProvider{name: Kraken, baseUrl: failing.URL, market: "XBTUSD", client: http.DefaultClient}},
+		&CoinbaseProvider{httpProvider{name: Coinbase, baseUrl: fixtures.URL + "/coinbase", market: "BTC-USD", client: http.DefaultClient}},
+	}}
+	points, err := fallback.History(fixtureStart, fixtureEnd)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(points) != 10 {
+		t.Fatalf("got %d days, want 10 from coinbase", len(points))
+	}
+	if _, err := fallback.Current(); err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+}
+
+func TestFallbackSkipsTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	fixtures := newFixtureServer()
+	defer fixtures.Close()
+
+	fallback := &Fallback{Providers: []PriceProvider{
+		&CoinGeckoProvider{httpProvider{name: CoinGecko, baseUrl: slow.URL, market: "bitcoin", client: &http.Client{Timeout: 50 * time.Millisecond}}},
+		&KrakenProvider{httpProvider{name: Kraken, baseUrl: fixtures.URL + "/kraken", market: "XBTUSD", client: http.DefaultClient}},
+	}}
+	price, err := fallback.Current()
+	if err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	if price != 21349.5 {
+		t.Fatalf("Current = %v, want kraken's 21349.5", price)
+	}
+}
+
+func TestFallbackReportsEveryError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	fallback := &Fallback{Providers: []PriceProvider{
+		&KrakenProvider{httpProvider{name: Kraken, baseUrl: failing.URL, market: "XBTUSD", client: http.DefaultClient}},
+		&BlockchainInfoProvider{httpProvider{name: BlockchainInfo, baseUrl: failing.URL, market: "USD", client: http.DefaultClient}},
+	}}
+	_, err := fallback.History(fixtureStart, fixtureEnd)
+	if err == nil {
+		t.Fatal("History succeeded with every provider down")
+	}
+	if !strings.Contains(err.Error(), "kraken") || strings.Contains(err.Error(), "blockchaininfo") {
+		t.Fatalf("error %q should name kraken and skip the unsupported blockchaininfo history", err)
+	}
+}
+
+func TestMalformedBodies(t *testing.T) {
+	parsers := map[string]func([]byte) error{
+		Messari:   func(b []byte) error { _, err := parseMessariHistory(b); return err },
+		Kraken:    func(b []byte) error { _, err := parseKrakenHistory(b); return err },
+		Coinbase:  func(b []byte) error { _, err := parseCoinbaseHistory(b); return err },
+		CoinGecko: func(b []byte) error { _, err := parseCoinGeckoHistory(b); return err },
+	}
+	for name, parse := range parsers {
+		for _, body := range []string{"", "<html>502 Bad Gateway</html>", `{"truncated": [`} {
+			if err := parse([]byte(body)); err == nil {
+				t.Errorf("%s parsed %q without an error", name, body)
+			}
+		}
+	}
+
+	garbage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer garbage.Close()
+	for _, cfg := range fixtureConfig(garbage.URL) {
+		provider, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price, err := provider.Current(); err == nil {
+			t.Errorf("%s Current parsed a malformed body as %v", cfg.Name, price)
+		}
+	}
+}
+
+func TestConfiguredDefaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Config
+		names []string
+		want  string
+	}{
+		{"no priceProviders", config.Config{}, nil, "blockchaininfo,kraken,messari"},
+		{"named default", config.Config{}, []string{"kraken"}, "kraken"},
+		{"configured", config.Config{PriceProviders: []config.PriceProviderConfig{{Name: Coinbase, BaseUrl: "http://localhost"}}}, nil, "coinbase"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fallback, err := Configured(&test.cfg, test.names)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fallback.Name(); got != test.want {
+				t.Fatalf("providers = %s, want %s", got, test.want)
+			}
+		})
+	}
+	if _, err := Configured(&config.Config{PriceProviders: []config.PriceProviderConfig{{Name: Coinbase, BaseUrl: "http://localhost"}}}, []string{"kraken"}); err == nil {
+		t.Fatal("want an error for a provider missing from priceProviders")
+	}
+}
+
+func TestFXProviderParsesFixture(t *testing.T) {
+	server := newFixtureServer()
+	defer server.Close()
+
+	provider, err := NewFXProvider(&config.Config{FxRateUrl: server.URL + "/frankfurter"}, "eur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := provider.History(fixtureStart, fixtureEnd)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	// working days only
+	if len(points) != 8 {
+		t.Fatalf("got %d days, want 8", len(points))
+	}
+	for i, p := range points {
+		if i > 0 && p.Timestamp <= points[i-1].Timestamp {
+			t.Fatalf("days out of order at %s", p.Time())
+		}
+		if p.OpenPrice < 0.95 || p.OpenPrice > 1 || p.ClosePrice != p.OpenPrice {
+			t.Fatalf("rate on %s = %+v, want EUR per USD", p.Time(), p)
+		}
+	}
+	rate, err := provider.Current()
+	if err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	if rate != 0.98833 {
+		t.Fatalf("Current = %v, want 0.98833", rate)
+	}
+
+	if _, err := NewFXProvider(&config.Config{}, "usd"); err == nil {
+		t.Fatal("want an error for USD rates")
+	}
+	for _, body := range []string{"", "<html></html>", `{"message":"not found"}`, `{"rates":{"22/07/2022":{"EUR":1}}}`} {
+		if _, err := parseFrankfurterHistory([]byte(body), "EUR"); err == nil {
+			t.Fatalf("parseFrankfurterHistory(%q) returned no error", body)
+		}
+	}
+}
+
+func TestNetworkProviderParsesFixture(t *testing.T) {
+	server := newFixtureServer()
+	defer server.Close()
+	provider := NewNetworkProvider(&config.Config{NetworkDataUrl: server.URL + "/mempool"})
+
+	day := func(date string) int64 {
+		d, _ := time.Parse(dateLayout, date)
+		return d.Unix()
+	}
+	points, err := provider.History(time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := []pricedata.NetworkPoint{
+		{Timestamp: day("2024-03-28"), Height: 836640, Difficulty: 83126997340024.53, Subsidy: 6.25},
+		{Timestamp: day("2024-04-10"), Height: 838656, Difficulty: 86388558925171.1, Subsidy: 6.25},
+		{Timestamp: day("2024-04-20"), Height: 840000, Difficulty: 86388558925171.1, Subsidy: 3.125},
+		{Timestamp: day("2024-04-24"), Height: 840672, Difficulty: 88104191118793.16, Subsidy: 3.125},
+	}
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("History = %+v, want the retargets and the halving in range: %+v", points, want)
+	}
+
+	points, err = provider.History(time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	tip := points[len(points)-1]
+	if len(points) != 7 || tip.Timestamp != Midnight(time.Now()).Unix() || tip.Height != 843000 || tip.Difficulty != 83148355189239.77 {
+		t.Fatalf("History = %+v, want every retarget, the halving and today's tip", points)
+	}
+
+	for _, body := range []string{"", "[]", "[[1715167440, 842688]]"} {
+		if _, err := parseDifficultyAdjustments([]byte(body)); err == nil {
+			t.Fatalf("parseDifficultyAdjustments(%q) returned no error", body)
+		}
+	}
+}
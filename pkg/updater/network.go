@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/priceprovider"
+	"fmt"
+	"sort"
+)
+
+// NetworkReplacement is a stored network day whose fetched point differs.
+type NetworkReplacement struct {
+	Old pricedata.NetworkPoint
+	New pricedata.NetworkPoint
+}
+
+// NetworkPlan is what an update would do to the network data file.
+type NetworkPlan struct {
+	Added     []pricedata.NetworkPoint
+	Replaced  []NetworkReplacement
+	Unchanged int
+}
+
+func (p NetworkPlan) Empty() bool {
+	return len(p.Added) == 0 && len(p.Replaced) == 0
+}
+
+// Lines describes the plan like Plan.Lines does.
+func (p NetworkPlan) Lines() []string {
+	lines := []string{}
+	for _, a := range p.Added {
+		lines = append(lines, fmt.Sprintf("+ %s height %d difficulty %v subsidy %v", formatDay(a.Timestamp), a.Height, a.Difficulty, a.Subsidy))
+	}
+	for _, r := range p.Replaced {
+		lines = append(lines, fmt.Sprintf("~ %s height %d -> %d difficulty %v -> %v subsidy %v -> %v", formatDay(r.New.Timestamp),
+			r.Old.Height, r.New.Height, r.Old.Difficulty, r.New.Difficulty, r.Old.Subsidy, r.New.Subsidy))
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i][2:12] < lines[j][2:12] })
+	return lines
+}
+
+// NewNetworkPlan compares fetched network points against the stored ones the
+// way NewPlan compares bars.
+func NewNetworkPlan(stored, fetched []pricedata.NetworkPoint, overwrite bool) NetworkPlan {
+	byDay := map[int64]pricedata.NetworkPoint{}
+	for _, p := range stored {
+		day := pricedata.Day(p.Time())
+		if _, ok := byDay[day]; !ok {
+			byDay[day] = p
+		}
+	}
+
+	plan := NetworkPlan{}
+	seen := map[int64]bool{}
+	for _, p := range fetched {
+		if p.Difficulty <= 0 {
+			continue
+		}
+		day := pricedata.Day(p.Time())
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		p.Timestamp = priceprovider.Midnight(p.Time()).Unix()
+
+		old, ok := byDay[day]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, p)
+		case overwrite && old != p:
+			plan.Replaced = append(plan.Replaced, NetworkReplacement{Old: old, New: p})
+		default:
+			plan.Unchanged++
+		}
+	}
+	sort.Slice(plan.Added, func(i, j int) bool { return plan.Added[i].Timestamp < plan.Added[j].Timestamp })
+	sort.Slice(plan.Replaced, func(i, j int) bool { return plan.Replaced[i].New.Timestamp < plan.Replaced[j].New.Timestamp })
+	return plan
+}
+
+// Apply returns stored with the plan's replacements made and its added days
+// merged in date order.
+func (p NetworkPlan) Apply(stored []pricedata.NetworkPoint) []pricedata.NetworkPoint {
+	replaced := map[int64]pricedata.NetworkPoint{}
+	for _, r := range p.Replaced {
+		replaced[r.Old.Timestamp] = r.New
+	}
+	updated := make([]pricedata.NetworkPoint, 0, len(stored)+len(p.Added))
+	for _, s := range stored {
+		if r, ok := replaced[s.Timestamp]; ok {
+			s = r
+		}
+		updated = append(updated, s)
+	}
+	updated = append(updated, p.Added...)
+	sort.SliceStable(updated, func(i, j int) bool { return updated[i].Timestamp < updated[j].Timestamp })
+	return updated
+}
@@ -0,0 +1,45 @@
+package updater
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func networkPoint(d int64, difficulty float64) pricedata.NetworkPoint {
+	return pricedata.NetworkPoint{Timestamp: 1656633600 + d*day, Difficulty: difficulty, Subsidy: 6.25}
+}
+
+func TestNetworkPlan(t *testing.T) {
+	stored := []pricedata.NetworkPoint{networkPoint(0, 100), networkPoint(14, 110)}
+	fetched := []pricedata.NetworkPoint{networkPoint(28, 120), networkPoint(14, 111), networkPoint(20, 0)}
+
+	plan := NewNetworkPlan(stored, fetched, false)
+	if !reflect.DeepEqual(plan.Added, []pricedata.NetworkPoint{networkPoint(28, 120)}) || len(plan.Replaced) != 0 || plan.Unchanged != 1 {
+		t.Fatalf("plan = %+v, want the new retarget added and the differing one kept", plan)
+	}
+	plan = NewNetworkPlan(stored, fetched, true)
+	if len(plan.Replaced) != 1 || plan.Replaced[0].New != networkPoint(14, 111) {
+		t.Fatalf("plan = %+v, want the differing retarget replaced with overwrite", plan)
+	}
+
+	// the first update creates the file
+	path := filepath.Join(t.TempDir(), "NetworkData.json")
+	for _, update := range []NetworkPlan{NewNetworkPlan(nil, stored, false), plan} {
+		err := pricedata.UpdateNetwork(path, 0, func(points []pricedata.NetworkPoint) ([]pricedata.NetworkPoint, error) {
+			return update.Apply(points), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	series, err := pricedata.LoadNetwork(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pricedata.NetworkPoint{networkPoint(0, 100), networkPoint(14, 111), networkPoint(28, 120)}
+	if got := series.Points(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("file holds %+v, want %+v", got, want)
+	}
+}
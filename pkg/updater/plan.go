@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"Mining-Profitability/pkg/priceprovider"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Replacement is a stored day whose fetched bar differs.
+type Replacement struct {
+	Old pricedata.PricePoint
+	New pricedata.PricePoint
+}
+
+// Plan is what an update would do to a store.
+type Plan struct {
+	Added    []pricedata.PricePoint
+	Replaced []Replacement
+	// Unchanged counts fetched days that are already stored, including
+	// differing days kept because overwrite is off.
+	Unchanged int
+}
+
+func (p Plan) Empty() bool {
+	return len(p.Added) == 0 && len(p.Replaced) == 0
+}
+
+// Lines describes the plan one day per line in date order, "+" for added days
+// and "~" for replaced ones.
+func (p Plan) Lines() []string {
+	lines := []string{}
+	for _, a := range p.Added {
+		lines = append(lines, fmt.Sprintf("+ %s open %v close %v", formatDay(a.Timestamp), a.OpenPrice, a.ClosePrice))
+	}
+	for _, r := range p.Replaced {
+		lines = append(lines, fmt.Sprintf("~ %s open %v -> %v close %v -> %v", formatDay(r.New.Timestamp),
+			r.Old.OpenPrice, r.New.OpenPrice, r.Old.ClosePrice, r.New.ClosePrice))
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i][2:12] < lines[j][2:12] })
+	return lines
+}
+
+// NewPlan compares fetched bars against the stored points. Days the store is
+// missing are added; days it already has are only replaced when overwrite is
+// set and the bars differ.
+func NewPlan(stored, fetched []pricedata.PricePoint, overwrite bool) Plan {
+	byDay := map[int64]pricedata.PricePoint{}
+	for _, p := range stored {
+		day := pricedata.Day(p.Time())
+		if _, ok := byDay[day]; !ok {
+			byDay[day] = p
+		}
+	}
+
+	plan := Plan{}
+	seen := map[int64]bool{}
+	for _, p := range fetched {
+		if p.OpenPrice <= 0 {
+			continue
+		}
+		day := pricedata.Day(p.Time())
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		p.Timestamp = priceprovider.Midnight(p.Time()).Unix()
+
+		old, ok := byDay[day]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, p)
+		case overwrite && old != p:
+			plan.Replaced = append(plan.Replaced, Replacement{Old: old, New: p})
+		default:
+			plan.Unchanged++
+		}
+	}
+	sort.Slice(plan.Added, func(i, j int) bool { return plan.Added[i].Timestamp < plan.Added[j].Timestamp })
+	sort.Slice(plan.Replaced, func(i, j int) bool { return plan.Replaced[i].New.Timestamp < plan.Replaced[j].New.Timestamp })
+	return plan
+}
+
+// Apply returns stored with the plan's replacements made in place and its
+// added days merged in date order.
+func (p Plan) Apply(stored []pricedata.PricePoint) []pricedata.PricePoint {
+	replaced := map[int64]pricedata.PricePoint{}
+	for _, r := range p.Replaced {
+		replaced[r.Old.Timestamp] = r.New
+	}
+	updated := make([]pricedata.PricePoint, 0, len(stored)+len(p.Added))
+	for _, s := range stored {
+		if r, ok := replaced[s.Timestamp]; ok {
+			s = r
+		}
+		updated = append(updated, s)
+	}
+	updated = append(updated, p.Added...)
+	sort.SliceStable(updated, func(i, j int) bool { return updated[i].Timestamp < updated[j].Timestamp })
+	return updated
+}
+
+func formatDay(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+}
@@ -0,0 +1,83 @@
+package updater
+
+import (
+	"Mining-Profitability/pkg/pricedata"
+	"reflect"
+	"testing"
+)
+
+const day = 24 * 60 * 60
+
+func point(d int64, open float64) pricedata.PricePoint {
+	return pricedata.PricePoint{Timestamp: 1656633600 + d*day, OpenPrice: open}
+}
+
+func TestNewPlan(t *testing.T) {
+	stored := []pricedata.PricePoint{point(0, 100), point(1, 110)}
+	tests := []struct {
+		name      string
+		fetched   []pricedata.PricePoint
+		overwrite bool
+		added     []pricedata.PricePoint
+		replaced  []Replacement
+		unchanged int
+	}{
+		{
+			name:      "adds missing days in date order",
+			fetched:   []pricedata.PricePoint{point(3, 130), point(1, 110), point(2, 120)},
+			added:     []pricedata.PricePoint{point(2, 120), point(3, 130)},
+			unchanged: 1,
+		},
+		{
+			name:      "keeps differing days without overwrite",
+			fetched:   []pricedata.PricePoint{point(1, 111)},
+			unchanged: 1,
+		},
+		{
+			name:      "replaces differing days with overwrite",
+			fetched:   []pricedata.PricePoint{point(0, 100), point(1, 111)},
+			overwrite: true,
+			replaced:  []Replacement{{Old: point(1, 110), New: point(1, 111)}},
+			unchanged: 1,
+		},
+		{
+			name:    "moves bars to midnight and drops duplicates and empty bars",
+			fetched: []pricedata.PricePoint{{Timestamp: point(2, 0).Timestamp + 3600, OpenPrice: 120}, point(2, 121), point(3, 0)},
+			added:   []pricedata.PricePoint{point(2, 120)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := NewPlan(stored, test.fetched, test.overwrite)
+			if len(plan.Added) != len(test.added) || (len(test.added) > 0 && !reflect.DeepEqual(plan.Added, test.added)) {
+				t.Fatalf("Added = %+v, want %+v", plan.Added, test.added)
+			}
+			if len(plan.Replaced) != len(test.replaced) || (len(test.replaced) > 0 && !reflect.DeepEqual(plan.Replaced, test.replaced)) {
+				t.Fatalf("Replaced = %+v, want %+v", plan.Replaced, test.replaced)
+			}
+			if plan.Unchanged != test.unchanged {
+				t.Fatalf("Unchanged = %d, want %d", plan.Unchanged, test.unchanged)
+			}
+			if plan.Empty() != (len(test.added) == 0 && len(test.replaced) == 0) {
+				t.Fatalf("Empty = %v", plan.Empty())
+			}
+		})
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	stored := []pricedata.PricePoint{point(0, 100), point(2, 120)}
+	plan := NewPlan(stored, []pricedata.PricePoint{point(1, 110), point(2, 121), point(3, 130)}, true)
+	got := plan.Apply(stored)
+	want := []pricedata.PricePoint{point(0, 100), point(1, 110), point(2, 121), point(3, 130)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply = %+v, want %+v", got, want)
+	}
+	if stored[1].OpenPrice != 120 {
+		t.Fatal("Apply changed the stored points it was given")
+	}
+	lines := plan.Lines()
+	if len(lines) != 3 || lines[0][:12] != "+ 2022-07-02" || lines[1][:12] != "~ 2022-07-03" {
+		t.Fatalf("Lines = %q", lines)
+	}
+}
@@ -12,6 +12,8 @@ type Date struct {
 type Interface interface {
 	DateToUnixTimestamp(start string) (timestamp string, err error)
 	RegularDateToUnix(start string) (days float64, err error)
+	DaysBetweenDates(start string, end time.Time) (days float64, err error)
+	ParseDate(date string) (time.Time, error)
 }
 
 func New() *Date {
@@ -28,13 +30,20 @@ func (d *Date) DateToUnixTimestamp(start string) (timestamp string, err error) {
 }
 
 func (d *Date) RegularDateToUnix(start string) (days float64, err error) {
+	return d.DaysBetweenDates(start, time.Now())
+}
+
+// DaysBetweenDates returns the whole days from start to end.
+func (d *Date) DaysBetweenDates(start string, end time.Time) (days float64, err error) {
 	t, err := time.Parse("01/02/2006", start)
 	if err != nil {
 		return
 	}
-	b := t.Unix()
-	tm := time.Unix(b, 0)
-	durationSinceStart := time.Since(tm)
+	durationSinceStart := end.Sub(t)
 	days = durationSinceStart.Hours() / 24
 	return math.Floor(days), err
 }
+
+func (d *Date) ParseDate(date string) (time.Time, error) {
+	return time.Parse("01/02/2006", date)
+}